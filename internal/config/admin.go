@@ -0,0 +1,19 @@
+package config
+
+import "errors"
+
+// Admin 管理接口配置
+type Admin struct {
+	Enable bool   `yaml:"enable"` // 是否启用管理接口
+	Token  string `yaml:"token"`  // 管理接口的访问令牌, 与 emby 的 api_key 相互独立
+}
+
+func (a *Admin) Init() error {
+	if !a.Enable {
+		return nil
+	}
+	if len(a.Token) < 16 {
+		return errors.New("admin.token 配置错误, 开启管理接口时, 令牌长度不能少于 16 位")
+	}
+	return nil
+}
@@ -2,10 +2,35 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	// DefaultCacheMaxSize 内存缓存默认的容量上限
+	DefaultCacheMaxSize = "100m"
+
+	// DefaultCacheMaxNum 内存缓存默认的最大条目数
+	DefaultCacheMaxNum = 8092
+
+	// DefaultCacheDiskDir 磁盘缓存默认的存放目录名称
+	DefaultCacheDiskDir = "cache-data"
+
+	// DefaultCacheDiskMaxSize 磁盘缓存默认的容量上限
+	DefaultCacheDiskMaxSize = "1g"
+
+	// CacheBackendMemory 缓存存储在进程内存中
+	CacheBackendMemory = "memory"
+
+	// CacheBackendRedis 缓存存储在 redis 中, 多个实例之间共享
+	CacheBackendRedis = "redis"
+
+	// DefaultCacheRedisPrefix redis 缓存默认的 key 前缀
+	DefaultCacheRedisPrefix = "ge2o:cache:"
+)
+
 // durationMap 字符串配置映射成 time.Duration
 var durationMap = map[string]time.Duration{
 	"d": time.Hour * 24,
@@ -14,35 +39,200 @@ var durationMap = map[string]time.Duration{
 	"s": time.Second,
 }
 
+// sizeMap 字符串配置映射成字节数
+var sizeMap = map[string]int64{
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+}
+
 type Cache struct {
-	Enable  bool          `yaml:"enable"`  // 是否启用缓存
-	Expired string        `yaml:"expired"` // 缓存过期时间
+	Enable  bool          `yaml:"enable"`   // 是否启用缓存
+	Expired string        `yaml:"expired"`  // 缓存过期时间
+	MaxSize string        `yaml:"max-size"` // 内存缓存容量上限 (按压缩后的响应体大小计算), 可配置单位: k, m, g
+	MaxNum  int           `yaml:"max-num"`  // 内存缓存最大条目数
+	Backend string        `yaml:"backend"`  // 缓存存储后端: memory, redis
+	Redis   CacheRedis    `yaml:"redis"`    // redis 缓存配置
+	Disk    CacheDisk     `yaml:"disk"`     // 磁盘缓存配置
+	Routes  []*CacheRoute `yaml:"routes"`   // 自定义缓存规则, 优先于内置的缓存规则匹配
 	expired time.Duration // 配置初始化转换之后的标准时间对象
+	maxSize int64         // 配置初始化转换之后的字节数
+}
+
+// CacheDisk 磁盘缓存配置
+//
+// 启用后, 缓存会同步写入到磁盘中, 程序重启后自动加载未过期的缓存
+type CacheDisk struct {
+	Enable  bool   `yaml:"enable"`   // 是否启用磁盘缓存
+	Dir     string `yaml:"dir"`      // 缓存存放目录, 相对路径基于数据根目录
+	MaxSize string `yaml:"max-size"` // 磁盘缓存容量上限, 可配置单位: k, m, g
+	maxSize int64  // 配置初始化转换之后的字节数
+}
+
+// CacheRedis redis 缓存配置
+//
+// 多个实例连接同一个 redis 时, 缓存以及缓存空间在实例之间共享
+type CacheRedis struct {
+	Addr     string `yaml:"addr"`     // 服务地址 host:port
+	Password string `yaml:"password"` // 访问密码
+	DB       int    `yaml:"db"`       // 数据库编号
+	Prefix   string `yaml:"prefix"`   // key 前缀, 不同的实例组可以通过前缀隔离缓存
 }
 
 func (c *Cache) ExpiredDuration() time.Duration {
 	return c.expired
 }
 
+// MaxSizeBytes 内存缓存容量上限 (Byte)
+func (c *Cache) MaxSizeBytes() int64 {
+	return c.maxSize
+}
+
 func (c *Cache) Init() error {
 	if len(c.Expired) == 0 {
 		// 缓存默认过期时间一天
 		c.expired = time.Hour * 24
 	} else {
-		timeFlag := c.Expired[len(c.Expired)-1:]
-		duration, ok := durationMap[timeFlag]
-		if !ok {
-			return fmt.Errorf("cache.expired 配置错误: %s, 支持的时间单位: s, m, h, d", timeFlag)
-		}
-		base, err := strconv.Atoi(c.Expired[:len(c.Expired)-1])
+		expired, err := parseDuration(c.Expired)
 		if err != nil {
 			return fmt.Errorf("cache.expired 配置错误: %v", err)
 		}
-		if base < 1 {
-			return fmt.Errorf("cache.exipred 配置错误: %d, 值需大于 0", base)
+		c.expired = expired
+	}
+
+	if strings.TrimSpace(c.MaxSize) == "" {
+		c.MaxSize = DefaultCacheMaxSize
+	}
+	size, err := parseSize(c.MaxSize)
+	if err != nil {
+		return fmt.Errorf("cache.max-size 配置错误: %v", err)
+	}
+	c.maxSize = size
+
+	if c.MaxNum < 0 {
+		return fmt.Errorf("cache.max-num 配置错误: %d, 值不能小于 0", c.MaxNum)
+	}
+	if c.MaxNum == 0 {
+		c.MaxNum = DefaultCacheMaxNum
+	}
+
+	c.Backend = strings.ToLower(strings.TrimSpace(c.Backend))
+	switch c.Backend {
+	case "":
+		c.Backend = CacheBackendMemory
+	case CacheBackendMemory:
+	case CacheBackendRedis:
+		if err := c.Redis.init(); err != nil {
+			return fmt.Errorf("cache.redis 配置错误: %v", err)
 		}
-		c.expired = time.Duration(base) * duration
+	default:
+		return fmt.Errorf("cache.backend 配置错误: %s, 可选值: %s, %s", c.Backend, CacheBackendMemory, CacheBackendRedis)
+	}
+
+	if err := c.Disk.init(); err != nil {
+		return fmt.Errorf("cache.disk 配置错误: %v", err)
 	}
 
+	for i, r := range c.Routes {
+		if r == nil {
+			return fmt.Errorf("cache.routes 配置错误, 第 %d 条规则为空", i+1)
+		}
+		if err := r.Init(); err != nil {
+			return fmt.Errorf("cache.routes 配置错误, 第 %d 条规则: %v", i+1, err)
+		}
+	}
 	return nil
 }
+
+// MatchRoute 获取第一个匹配请求 uri 的自定义缓存规则
+func (c *Cache) MatchRoute(uri string) (*CacheRoute, bool) {
+	for _, r := range c.Routes {
+		if r.reg.MatchString(uri) {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// parseDuration 将带单位的时间字符串转换成 time.Duration, 如: 10m, 1d
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("时间不能为空")
+	}
+	timeFlag := s[len(s)-1:]
+	duration, ok := durationMap[timeFlag]
+	if !ok {
+		return 0, fmt.Errorf("%s, 支持的时间单位: s, m, h, d", timeFlag)
+	}
+	base, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil {
+		return 0, err
+	}
+	if base < 1 {
+		return 0, fmt.Errorf("%d, 值需大于 0", base)
+	}
+	return time.Duration(base) * duration, nil
+}
+
+// DirPath 磁盘缓存目录的绝对路径
+func (cd *CacheDisk) DirPath() string {
+	if filepath.IsAbs(cd.Dir) {
+		return cd.Dir
+	}
+	return filepath.Join(BasePath, cd.Dir)
+}
+
+// MaxSizeBytes 磁盘缓存容量上限 (Byte)
+func (cd *CacheDisk) MaxSizeBytes() int64 {
+	return cd.maxSize
+}
+
+// init 校验参数并设置默认值
+func (cd *CacheDisk) init() error {
+	if strings.TrimSpace(cd.Dir) == "" {
+		cd.Dir = DefaultCacheDiskDir
+	}
+	if strings.TrimSpace(cd.MaxSize) == "" {
+		cd.MaxSize = DefaultCacheDiskMaxSize
+	}
+	size, err := parseSize(cd.MaxSize)
+	if err != nil {
+		return fmt.Errorf("max-size: %v", err)
+	}
+	cd.maxSize = size
+	return nil
+}
+
+// init 校验参数并设置默认值
+func (cr *CacheRedis) init() error {
+	if strings.TrimSpace(cr.Addr) == "" {
+		return fmt.Errorf("addr 不能为空")
+	}
+	if cr.DB < 0 {
+		return fmt.Errorf("db: %d, 值不能小于 0", cr.DB)
+	}
+	if cr.Prefix == "" {
+		cr.Prefix = DefaultCacheRedisPrefix
+	}
+	return nil
+}
+
+// parseSize 将带单位的容量字符串转换成字节数, 如: 512m, 2g
+func parseSize(s string) (int64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, fmt.Errorf("容量不能为空")
+	}
+	unit, ok := sizeMap[s[len(s)-1:]]
+	if !ok {
+		return 0, fmt.Errorf("%s, 支持的容量单位: k, m, g", s)
+	}
+	base, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", s, err)
+	}
+	if base < 1 {
+		return 0, fmt.Errorf("%s, 值需大于 0", s)
+	}
+	return base * unit, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// CacheRoute 自定义缓存规则
+//
+// 匹配规则的请求会被缓存, 并按照规则计算 cache key
+type CacheRoute struct {
+	// Pattern 匹配请求 uri 的正则表达式
+	Pattern string `yaml:"pattern"`
+
+	// Expired 缓存过期时间, 可配置单位: d, h, m, s, 为空时使用接口默认的过期时间
+	Expired string `yaml:"expired"`
+
+	// Params 参与 cache key 计算的请求参数
+	Params CacheKeyFilter `yaml:"params"`
+
+	// Headers 参与 cache key 计算的请求头
+	Headers CacheKeyFilter `yaml:"headers"`
+
+	// PerUser 是否按照 emby 用户区分缓存
+	PerUser bool `yaml:"per-user"`
+
+	// reg 编译后的正则表达式
+	reg *regexp.Regexp
+
+	// expired 配置初始化转换之后的标准时间对象
+	expired time.Duration
+}
+
+// CacheKeyFilter 过滤参与 cache key 计算的请求参数或请求头, 名称不区分大小写
+//
+// 内置忽略的参数 (如 PlaySessionId, X-Forwarded-For) 始终不参与计算
+type CacheKeyFilter struct {
+	// Include 只有列表中的名称参与计算, 为空时不限制
+	Include []string `yaml:"include"`
+
+	// Exclude 列表中的名称不参与计算
+	Exclude []string `yaml:"exclude"`
+}
+
+// Init 校验规则并编译正则表达式
+func (r *CacheRoute) Init() error {
+	if strs.AnyEmpty(r.Pattern) {
+		return fmt.Errorf("pattern 不能为空")
+	}
+	reg, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("pattern 编译失败: %v", err)
+	}
+	r.reg = reg
+
+	if r.Expired != "" {
+		expired, err := parseDuration(r.Expired)
+		if err != nil {
+			return fmt.Errorf("expired 配置错误: %v", err)
+		}
+		r.expired = expired
+	}
+	return nil
+}
+
+// ExpiredDuration 规则配置的缓存过期时间, 未配置时返回 0
+func (r *CacheRoute) ExpiredDuration() time.Duration {
+	return r.expired
+}
+
+// Keep 判断名称是否参与 cache key 计算
+func (f *CacheKeyFilter) Keep(name string) bool {
+	equal := func(s string) bool { return strings.EqualFold(s, name) }
+	if len(f.Include) > 0 && !slices.ContainsFunc(f.Include, equal) {
+		return false
+	}
+	return !slices.ContainsFunc(f.Exclude, equal)
+}
@@ -0,0 +1,151 @@
+package config_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestCacheDisk(t *testing.T) {
+	old := config.BasePath
+	config.BasePath = t.TempDir()
+	defer func() { config.BasePath = old }()
+
+	c := config.Cache{Disk: config.CacheDisk{Enable: true}}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Disk.DirPath() != filepath.Join(config.BasePath, config.DefaultCacheDiskDir) {
+		t.Fatalf("默认目录异常: %s", c.Disk.DirPath())
+	}
+	if c.Disk.MaxSizeBytes() != 1<<30 {
+		t.Fatalf("默认容量异常: %d", c.Disk.MaxSizeBytes())
+	}
+
+	tests := []struct {
+		size string
+		want int64
+		err  bool
+	}{
+		{size: "512k", want: 512 << 10},
+		{size: "200M", want: 200 << 20},
+		{size: "2g", want: 2 << 30},
+		{size: "10t", err: true},
+		{size: "0m", err: true},
+		{size: "abcm", err: true},
+	}
+	for _, tt := range tests {
+		c := config.Cache{Disk: config.CacheDisk{MaxSize: tt.size}}
+		err := c.Init()
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: 期望配置报错", tt.size)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.size, err)
+			continue
+		}
+		if got := c.Disk.MaxSizeBytes(); got != tt.want {
+			t.Errorf("%s: 期望 %d, 实际 %d", tt.size, tt.want, got)
+		}
+	}
+}
+
+func TestCacheLimits(t *testing.T) {
+	c := config.Cache{}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxSizeBytes() != 100<<20 || c.MaxNum != config.DefaultCacheMaxNum {
+		t.Fatalf("默认容量异常: %d, %d", c.MaxSizeBytes(), c.MaxNum)
+	}
+
+	c = config.Cache{MaxSize: "20m", MaxNum: 100}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxSizeBytes() != 20<<20 || c.MaxNum != 100 {
+		t.Fatalf("容量配置异常: %d, %d", c.MaxSizeBytes(), c.MaxNum)
+	}
+
+	for _, c := range []config.Cache{{MaxSize: "20x"}, {MaxNum: -1}} {
+		if err := c.Init(); err == nil {
+			t.Errorf("期望配置报错: %+v", c)
+		}
+	}
+}
+
+func TestCacheRoutes(t *testing.T) {
+	c := config.Cache{Routes: []*config.CacheRoute{
+		{
+			Pattern: `(?i)/Shows/NextUp`,
+			Expired: "5m",
+			Params:  config.CacheKeyFilter{Exclude: []string{"fields"}},
+			Headers: config.CacheKeyFilter{Include: []string{"x-emby-token"}},
+			PerUser: true,
+		},
+		{Pattern: `(?i)/Items/Latest`},
+	}}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, ok := c.MatchRoute("/emby/shows/nextup?Limit=10")
+	if !ok || r.ExpiredDuration() != 5*time.Minute {
+		t.Fatalf("规则匹配异常: %v", ok)
+	}
+	if r.Params.Keep("Fields") || !r.Params.Keep("Limit") {
+		t.Fatal("params 过滤异常")
+	}
+	if !r.Headers.Keep("X-Emby-Token") || r.Headers.Keep("User-Agent") {
+		t.Fatal("headers 过滤异常")
+	}
+
+	r, ok = c.MatchRoute("/Users/1/Items/Latest")
+	if !ok || r.ExpiredDuration() != 0 {
+		t.Fatal("未配置 expired 的规则匹配异常")
+	}
+	if _, ok := c.MatchRoute("/Items/1/PlaybackInfo"); ok {
+		t.Fatal("期望规则不匹配")
+	}
+
+	for _, r := range []*config.CacheRoute{{}, {Pattern: "("}, {Pattern: "a", Expired: "1x"}, nil} {
+		c := config.Cache{Routes: []*config.CacheRoute{r}}
+		if err := c.Init(); err == nil {
+			t.Errorf("期望配置报错: %+v", r)
+		}
+	}
+}
+
+func TestCacheBackend(t *testing.T) {
+	c := config.Cache{}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Backend != config.CacheBackendMemory {
+		t.Fatalf("默认存储后端异常: %s", c.Backend)
+	}
+
+	c = config.Cache{Backend: "Redis", Redis: config.CacheRedis{Addr: "127.0.0.1:6379"}}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Backend != config.CacheBackendRedis || c.Redis.Prefix != config.DefaultCacheRedisPrefix {
+		t.Fatalf("redis 配置异常: %s, %s", c.Backend, c.Redis.Prefix)
+	}
+
+	invalid := []config.Cache{
+		{Backend: "memcached"},
+		{Backend: "redis"},
+		{Backend: "redis", Redis: config.CacheRedis{Addr: "127.0.0.1:6379", DB: -1}},
+	}
+	for _, c := range invalid {
+		if err := c.Init(); err == nil {
+			t.Errorf("期望配置报错: %+v", c)
+		}
+	}
+}
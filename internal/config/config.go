@@ -1,11 +1,20 @@
 package config
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"sync/atomic"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
 	"gopkg.in/yaml.v3"
 )
@@ -13,6 +22,8 @@ import (
 type Config struct {
 	// Emby emby 相关配置
 	Emby *Emby `yaml:"emby"`
+	// EmbyUpstreams 额外的 emby 上游配置
+	EmbyUpstreams EmbyUpstreams `yaml:"emby-upstreams"`
 	// Openlist openlist 相关配置
 	Openlist *Openlist `yaml:"openlist"`
 	// VideoPreview 网盘转码链接代理配置
@@ -25,14 +36,34 @@ type Config struct {
 	Ssl *Ssl `yaml:"ssl"`
 	// Log 日志相关配置
 	Log *Log `yaml:"log"`
+	// Admin 管理接口相关配置
+	Admin *Admin `yaml:"admin"`
+	// Webhook emby 通知接收配置
+	Webhook *Webhook `yaml:"webhook"`
+	// RateLimit 获取直链的限流配置
+	RateLimit *RateLimit `yaml:"rate-limit"`
+	// Server 服务相关配置
+	Server *Server `yaml:"server"`
+	// Routes 自定义路由规则
+	Routes Routes `yaml:"routes"`
 }
 
-// C 全局唯一配置对象
-var C *Config
+// current 全局唯一配置对象, 热重载时整体替换
+var current atomic.Pointer[Config]
+
+// C 获取当前生效的全局配置对象
+//
+// 配置支持热重载, 同一流程中多次读取配置时, 可以只调用一次并复用返回的对象
+func C() *Config {
+	return current.Load()
+}
 
 // BasePath 配置文件所在的基础路径
 var BasePath string
 
+// filePath 当前加载的配置文件路径, 用于热重载
+var filePath string
+
 type Initializer interface {
 	// Init 配置初始化
 	Init() error
@@ -40,21 +71,35 @@ type Initializer interface {
 
 // ReadFromFile 从指定文件中读取配置
 func ReadFromFile(path string) error {
-	bytes, err := os.ReadFile(path)
+	if err := initBasePath(path); err != nil {
+		return fmt.Errorf("初始化 BasePath 失败: %v", err)
+	}
+
+	c, err := load(path)
 	if err != nil {
-		return fmt.Errorf("读取配置文件失败: %v", err)
+		return err
 	}
 
-	if err = initBasePath(path); err != nil {
-		return fmt.Errorf("初始化 BasePath 失败: %v", err)
+	filePath = path
+	setCurrent(c)
+	return nil
+}
+
+// load 读取配置文件, 生成一个全新的配置对象并完成初始化
+//
+// 任意配置项初始化失败都会返回错误, 不会影响当前正在使用的全局配置
+func load(path string) (*Config, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
 	}
 
-	C = new(Config)
-	if err := yaml.Unmarshal(bytes, C); err != nil {
-		return fmt.Errorf("解析配置文件失败: %v", err)
+	c := new(Config)
+	if err := yaml.Unmarshal(bytes, c); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
 
-	cVal := reflect.ValueOf(C).Elem()
+	cVal := reflect.ValueOf(c).Elem()
 	for i := 0; i < cVal.NumField(); i++ {
 		field := cVal.Field(i)
 
@@ -64,31 +109,82 @@ func ReadFromFile(path string) error {
 			field.Set(reflect.New(elmType))
 		}
 
+		// 使用环境变量覆盖配置值, 需要在初始化之前完成, 以便校验覆盖后的值
+		tag := strings.Split(cVal.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		if err := applyEnv(field, EnvPrefix+"_"+envName(tag)); err != nil {
+			return nil, fmt.Errorf("初始化配置文件失败: %v", err)
+		}
+
 		// 配置项初始化
 		if i, ok := field.Interface().(Initializer); ok {
 			if err := i.Init(); err != nil {
-				return fmt.Errorf("初始化配置文件失败: %v", err)
+				return nil, fmt.Errorf("初始化配置文件失败: %v", err)
 			}
 		}
 	}
 
-	return nil
+	if err := c.initUpstreams(); err != nil {
+		return nil, fmt.Errorf("初始化配置文件失败: %v", err)
+	}
+	return c, nil
+}
+
+// setCurrent 将初始化完成的配置对象设置为全局配置
+func setCurrent(c *Config) {
+	current.Store(c)
+	colors.SetEnabler(c.Log)
+	logs.SetJsonMode(c.Log.Format == LogFormatJson)
 }
 
 // ServerInternalRequestHost 服务内部自请求 host
+//
+// 优先使用 http 服务的第一个 tcp 监听地址, 其次使用 https 服务的地址
 func ServerInternalRequestHost() string {
 	p := "http://127.0.0.1:" + webport.HTTP
-	if C == nil {
+	c := C()
+	if c == nil {
 		return p
 	}
 
-	// 只开启了 https 端口
-	if C.Ssl.Enable && C.Ssl.SinglePort {
-		p = "https://127.0.0.1:" + webport.HTTPS
+	if !c.Ssl.Enable || !c.Ssl.SinglePort {
+		for _, l := range c.Server.HttpListeners() {
+			if host := l.LocalHost(); host != "" {
+				return "http://" + host
+			}
+		}
 	}
+	if c.Ssl.Enable {
+		for _, l := range c.Server.HttpsListeners() {
+			if host := l.LocalHost(); host != "" {
+				return "https://" + host
+			}
+		}
+	}
+
+	// 只监听了 unix 套接字, 无法进行自请求
 	return p
 }
 
+// internalToken 服务内部自请求的凭证, 每次启动时随机生成
+var internalToken = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+// InternalToken 获取服务内部自请求的凭证
+//
+// 自请求需要在 constant.HeaderInternalToken 请求头中携带该凭证, 避免外部请求伪造自请求
+func InternalToken() string {
+	return internalToken
+}
+
+// IsInternalRequest 判断请求头中是否携带了合法的自请求凭证
+func IsInternalRequest(header http.Header) bool {
+	token := header.Get(constant.HeaderInternalToken)
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(internalToken)) == 1
+}
+
 // initBasePath 初始化 BasePath
 func initBasePath(path string) error {
 	if filepath.IsAbs(path) {
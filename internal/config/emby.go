@@ -58,6 +58,15 @@ type Emby struct {
 	DownloadStrategy DlStrategy `yaml:"download-strategy"`
 	// LocalMediaRoot 本地媒体根路径
 	LocalMediaRoot string `yaml:"local-media-root"`
+
+	// Name 上游名称, 仅在 emby-upstreams 中配置, 默认上游的名称固定为 default
+	Name string `yaml:"name"`
+	// MatchPorts 匹配的监听端口, 仅在 emby-upstreams 中生效
+	MatchPorts []string `yaml:"match-ports"`
+	// MatchHosts 匹配的请求 Host, 仅在 emby-upstreams 中生效
+	MatchHosts []string `yaml:"match-hosts"`
+	// Path 上游独立的路径映射配置, 不配置时使用全局的 path 配置
+	Path *Path `yaml:"path"`
 }
 
 func (e *Emby) Init() error {
@@ -103,6 +112,12 @@ func (e *Emby) Init() error {
 		e.LocalMediaRoot = "/" + randoms.RandomHex(32)
 	}
 
+	if e.Path != nil {
+		if err := e.Path.Init(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
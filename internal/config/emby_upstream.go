@@ -0,0 +1,103 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// DefaultUpstreamName 默认 emby 上游 (即 emby 配置) 的名称
+const DefaultUpstreamName = "default"
+
+// EmbyUpstreams 额外的 emby 上游列表
+//
+// 请求根据监听端口或 Host 请求头匹配上游, 全部不匹配时使用默认的 emby 配置
+type EmbyUpstreams []*Emby
+
+// Init 配置初始化
+func (eu EmbyUpstreams) Init() error {
+	names := map[string]struct{}{DefaultUpstreamName: {}}
+	for i, e := range eu {
+		if e == nil {
+			return fmt.Errorf("第 %d 个上游配置为空", i+1)
+		}
+
+		if e.Name = strings.TrimSpace(e.Name); e.Name == "" {
+			return fmt.Errorf("第 %d 个上游未配置 name", i+1)
+		}
+		if _, ok := names[e.Name]; ok {
+			return fmt.Errorf("上游名称重复: %s", e.Name)
+		}
+		names[e.Name] = struct{}{}
+
+		if len(e.MatchPorts) == 0 && len(e.MatchHosts) == 0 {
+			return fmt.Errorf("上游 [%s] 至少需要配置 match-ports 或 match-hosts 其中一项", e.Name)
+		}
+		for j, host := range e.MatchHosts {
+			e.MatchHosts[j] = strings.ToLower(strings.TrimSpace(host))
+		}
+
+		if err := e.Init(); err != nil {
+			return fmt.Errorf("上游 [%s] 配置错误: %v", e.Name, err)
+		}
+	}
+	return nil
+}
+
+// initUpstreams 在所有配置项初始化完成后, 为上游补充默认值
+func (c *Config) initUpstreams() error {
+	if c.Emby == nil {
+		return errors.New("emby 配置不能为空")
+	}
+	c.Emby.Name = DefaultUpstreamName
+
+	for _, e := range c.AllEmby() {
+		if e.Path == nil {
+			// 没有独立配置路径映射时, 使用全局配置
+			e.Path = c.Path
+		}
+	}
+	return nil
+}
+
+// AllEmby 获取所有的 emby 上游, 第一个元素为默认上游
+func (c *Config) AllEmby() []*Emby {
+	return append([]*Emby{c.Emby}, c.EmbyUpstreams...)
+}
+
+// MatchEmby 根据监听端口以及请求 Host 匹配 emby 上游
+//
+// 按照配置顺序匹配第一个符合条件的上游, 都不匹配时返回默认上游
+func (c *Config) MatchEmby(port, host string) *Emby {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	for _, e := range c.EmbyUpstreams {
+		if host != "" && slices.Contains(e.MatchHosts, host) {
+			return e
+		}
+		if port != "" && slices.Contains(e.MatchPorts, port) {
+			return e
+		}
+	}
+	return c.Emby
+}
+
+// FindEmby 根据名称查找 emby 上游, 找不到时返回 false
+func (c *Config) FindEmby(name string) (*Emby, bool) {
+	if strs.AnyEmpty(name) {
+		return nil, false
+	}
+	for _, e := range c.AllEmby() {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return nil, false
+}
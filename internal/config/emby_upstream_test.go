@@ -0,0 +1,79 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestMatchEmby(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := `
+emby:
+  host: http://family:8096
+emby-upstreams:
+  - name: friends
+    host: http://friends:8096
+    mount-path: /mnt
+    match-hosts: [Friends.Example.com]
+    path:
+      emby2openlist:
+        - /movie:/朋友的电影
+  - name: test
+    host: http://test:8096
+    match-ports: ["8099"]
+path:
+  emby2openlist:
+    - /movie:/电影
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct{ port, host, want string }{
+		{"8097", "friends.example.com:8097", "friends"},
+		{"8097", "FRIENDS.example.com", "friends"},
+		{"8099", "192.168.1.2:8099", "test"},
+		{"8097", "192.168.1.2:8097", config.DefaultUpstreamName},
+	}
+	for _, c := range cases {
+		if got := config.C().MatchEmby(c.port, c.host).Name; got != c.want {
+			t.Errorf("匹配 (%s, %s), 期望: %s, 实际: %s", c.port, c.host, c.want, got)
+		}
+	}
+
+	// 未配置独立路径映射的上游使用全局配置
+	if res, _ := config.C().EmbyUpstreams[1].Path.MapEmby2Openlist("/movie/a.mkv"); res != "/电影/a.mkv" {
+		t.Errorf("test 上游路径映射错误: %s", res)
+	}
+	if res, _ := config.C().EmbyUpstreams[0].Path.MapEmby2Openlist("/movie/a.mkv"); res != "/朋友的电影/a.mkv" {
+		t.Errorf("friends 上游路径映射错误: %s", res)
+	}
+}
+
+func TestEmbyUpstreamsInvalid(t *testing.T) {
+	contents := []string{
+		// 名称重复
+		"emby:\n  host: http://a\nemby-upstreams:\n  - {name: b, host: http://b, match-ports: ['1']}\n  - {name: b, host: http://c, match-ports: ['2']}\n",
+		// 与默认上游重名
+		"emby:\n  host: http://a\nemby-upstreams:\n  - {name: default, host: http://b, match-ports: ['1']}\n",
+		// 缺少匹配条件
+		"emby:\n  host: http://a\nemby-upstreams:\n  - {name: b, host: http://b}\n",
+		// 缺少 host
+		"emby:\n  host: http://a\nemby-upstreams:\n  - {name: b, match-ports: ['1']}\n",
+	}
+	for _, content := range contents {
+		cfgPath := filepath.Join(t.TempDir(), "config.yml")
+		if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := config.ReadFromFile(cfgPath); err == nil {
+			t.Errorf("期望配置校验失败: %s", content)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix 环境变量覆盖配置的统一前缀
+	EnvPrefix = "GE2O"
+
+	// EnvFileSuffix 从文件中读取配置值的环境变量后缀, 适用于挂载的 secret
+	EnvFileSuffix = "_FILE"
+)
+
+// applyEnv 使用环境变量覆盖配置值
+//
+// 环境变量名由前缀与 yaml 标签逐级拼接而成, 标签中的 '-' 替换为 '_' 并转为大写,
+// 如 emby.host 对应 GE2O_EMBY_HOST, openlist.local-tree-gen.scan-prefixes
+// 对应 GE2O_OPENLIST_LOCAL_TREE_GEN_SCAN_PREFIXES;
+// 在变量名后追加 _FILE 后缀, 则会读取变量值所指向文件的内容作为配置值
+func applyEnv(v reflect.Value, name string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			break
+		}
+		if v.IsNil() {
+			// 没有相关的环境变量时, 保持 nil 值, 交由配置项自行初始化默认值
+			if !hasEnvPrefix(name + "_") {
+				return nil
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return applyEnv(v.Elem(), name)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			tag := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+			if tag == "" || tag == "-" {
+				continue
+			}
+			if err := applyEnv(v.Field(i), name+"_"+envName(tag)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	raw, ok, err := lookupEnv(name)
+	if err != nil || !ok {
+		return err
+	}
+	if err := setFromEnv(v, raw); err != nil {
+		return fmt.Errorf("环境变量 %s 转换失败: %v", name, err)
+	}
+	logs.Tip("使用环境变量 %s 覆盖配置", name)
+	return nil
+}
+
+// envName 将 yaml 标签转换为环境变量名片段
+func envName(tag string) string {
+	return strings.ToUpper(strings.ReplaceAll(tag, "-", "_"))
+}
+
+// hasEnvPrefix 判断是否存在以指定前缀开头的环境变量
+func hasEnvPrefix(prefix string) bool {
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupEnv 读取环境变量值, 优先读取变量本身, 其次读取 _FILE 变量指向的文件
+func lookupEnv(name string) (string, bool, error) {
+	val, ok := os.LookupEnv(name)
+	filePath, fileOk := os.LookupEnv(name + EnvFileSuffix)
+	if ok && fileOk {
+		return "", false, fmt.Errorf("环境变量 %s 与 %s 不能同时配置", name, name+EnvFileSuffix)
+	}
+	if ok {
+		return val, true, nil
+	}
+	if !fileOk {
+		return "", false, nil
+	}
+
+	bytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("读取环境变量 %s 指定的文件失败: %v", name+EnvFileSuffix, err)
+	}
+	return strings.TrimRight(string(bytes), "\r\n"), true, nil
+}
+
+// setFromEnv 将环境变量字符串转换为字段对应的类型并赋值
+//
+// 切片使用 ',' 分割元素, 以 '[' 开头时按照 yaml 格式整体解析, 其余复杂类型按照 yaml 格式解析
+func setFromEnv(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			return yaml.Unmarshal([]byte(raw), v.Addr().Interface())
+		}
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			elm := reflect.New(v.Type().Elem())
+			if err := setFromEnv(elm.Elem(), item); err != nil {
+				return err
+			}
+			items = reflect.Append(items, elm.Elem())
+		}
+		v.Set(items)
+	default:
+		return yaml.Unmarshal([]byte(raw), v.Addr().Interface())
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestEnvOverride(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(cfgPath, []byte("emby:\n  host: http://127.0.0.1:8096\nopenlist:\n  token: from-file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	secretPath := filepath.Join(dir, "token")
+	if err := os.WriteFile(secretPath, []byte("from-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GE2O_EMBY_HOST", "http://emby:8096")
+	t.Setenv("GE2O_EMBY_IMAGES_QUALITY", "80")
+	t.Setenv("GE2O_OPENLIST_TOKEN_FILE", secretPath)
+	t.Setenv("GE2O_OPENLIST_LOCAL_TREE_GEN_ENABLE", "true")
+	t.Setenv("GE2O_OPENLIST_LOCAL_TREE_GEN_REFRESH_INTERVAL", "30")
+	t.Setenv("GE2O_OPENLIST_LOCAL_TREE_GEN_SCAN_PREFIXES", "/movie, /tv")
+	t.Setenv("GE2O_PATH_EMBY2OPENLIST", "/movie:/电影,/tv:/电视剧")
+
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.C()
+	if c.Emby.Host != "http://emby:8096" {
+		t.Errorf("emby.host 覆盖失败: %s", c.Emby.Host)
+	}
+	if c.Emby.ImagesQuality != 80 {
+		t.Errorf("emby.images-quality 覆盖失败: %d", c.Emby.ImagesQuality)
+	}
+	if c.Openlist.Token != "from-secret" {
+		t.Errorf("openlist.token 覆盖失败: %s", c.Openlist.Token)
+	}
+	ltg := c.Openlist.LocalTreeGen
+	if !ltg.Enable || ltg.RefreshInterval != 30 {
+		t.Errorf("openlist.local-tree-gen 覆盖失败: %+v", ltg)
+	}
+	if !slices.Equal(ltg.ScanPrefixes, []string{"/movie", "/tv"}) {
+		t.Errorf("openlist.local-tree-gen.scan-prefixes 覆盖失败: %v", ltg.ScanPrefixes)
+	}
+	if res, ok := c.Path.MapEmby2Openlist("/movie/a.mkv"); !ok || res != "/电影/a.mkv" {
+		t.Errorf("path.emby2openlist 覆盖失败: %s", res)
+	}
+}
+
+func TestEnvOverrideInvalid(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(cfgPath, []byte("emby:\n  host: http://127.0.0.1:8096\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 覆盖后的值仍需通过校验
+	t.Setenv("GE2O_EMBY_IMAGES_QUALITY", "101")
+	if err := config.ReadFromFile(cfgPath); err == nil {
+		t.Fatal("期望校验失败")
+	}
+
+	t.Setenv("GE2O_EMBY_IMAGES_QUALITY", "abc")
+	if err := config.ReadFromFile(cfgPath); err == nil {
+		t.Fatal("期望类型转换失败")
+	}
+}
@@ -1,19 +1,36 @@
 package config
 
-import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+import "fmt"
+
+// 日志输出格式
+const (
+	LogFormatText = "text" // 文本格式, 适合直接在终端中查看
+	LogFormatJson = "json" // json 格式, 每行一个 json 对象, 适合采集到日志系统中
+)
 
 // Log 日志配置
 type Log struct {
-	DisableColor bool `yaml:"disable-color"` // 是否禁用彩色日志输出
+	DisableColor bool   `yaml:"disable-color"` // 是否禁用彩色日志输出
+	Format       string `yaml:"format"`        // 日志输出格式: text, json
 }
 
 // Init 配置初始化
+//
+// 颜色输出控制器在配置生效时才会被替换, 避免热重载失败时影响当前日志输出
 func (lc *Log) Init() error {
-	colors.SetEnabler(lc)
+	switch lc.Format {
+	case "":
+		lc.Format = LogFormatText
+	case LogFormatText, LogFormatJson:
+	default:
+		return fmt.Errorf("log.format 配置错误: %s, 可选值: text, json", lc.Format)
+	}
 	return nil
 }
 
 // EnableColor 标记是否启用颜色输出
+//
+// json 格式的日志不输出颜色
 func (lc *Log) EnableColor() bool {
-	return !lc.DisableColor
+	return !lc.DisableColor && lc.Format != LogFormatJson
 }
@@ -13,11 +13,34 @@ type Openlist struct {
 	// Host openlist 访问地址（如果 openlist 使用本地代理模式, 则这个地址必须配置公网可访问地址）
 	Host string `yaml:"host"`
 
+	// Instances 额外的 openlist 实例, 需要与主实例挂载相同的存储
+	//
+	// 请求失败时, 会自动切换到下一个健康的实例重试
+	Instances []*OpenlistInstance `yaml:"instances"`
+
 	// LocalTreeGen 本地目录树生成相关
 	LocalTreeGen *LocalTreeGen `yaml:"local-tree-gen"`
 }
 
+// OpenlistInstance openlist 实例
+type OpenlistInstance struct {
+	// Host 实例访问地址
+	Host string `yaml:"host"`
+	// Token 实例的访问密钥, 不配置时使用主实例的密钥
+	Token string `yaml:"token"`
+}
+
 func (a *Openlist) Init() error {
+	for i, ins := range a.Instances {
+		if ins == nil || strings.TrimSpace(ins.Host) == "" {
+			return fmt.Errorf("openlist.instances 配置错误, 第 %d 个实例未配置 host", i+1)
+		}
+		ins.Host = strings.TrimSpace(ins.Host)
+		if strings.TrimSpace(ins.Token) == "" {
+			ins.Token = a.Token
+		}
+	}
+
 	if a.LocalTreeGen == nil {
 		a.LocalTreeGen = new(LocalTreeGen)
 	}
@@ -28,6 +51,17 @@ func (a *Openlist) Init() error {
 	return nil
 }
 
+// AllInstances 获取所有的 openlist 实例, 主实例位于第一个
+//
+// 主实例未配置 host 时不会返回
+func (a *Openlist) AllInstances() []*OpenlistInstance {
+	res := make([]*OpenlistInstance, 0, len(a.Instances)+1)
+	if strings.TrimSpace(a.Host) != "" {
+		res = append(res, &OpenlistInstance{Host: a.Host, Token: a.Token})
+	}
+	return append(res, a.Instances...)
+}
+
 type LocalTreeGen struct {
 
 	// Enable 是否启用
@@ -1,40 +1,154 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
+	"gopkg.in/yaml.v3"
 )
 
 type Path struct {
-	// Emby2Openlist Emby 的路径前缀映射到 Openlist 的路径前缀, 两个路径使用 : 符号隔开
-	Emby2Openlist []string `yaml:"emby2openlist"`
+	// Emby2Openlist Emby 的路径映射到 Openlist 的路径
+	//
+	// 兼容旧版本使用 : 符号隔开的字符串格式, 也支持 from/to 结构化格式
+	Emby2Openlist []*PathMapping `yaml:"emby2openlist"`
+}
+
+// PathMapping 单条路径映射规则
+type PathMapping struct {
+	// From emby 路径前缀, 开启 Regex 时为正则表达式
+	From string `yaml:"from"`
+
+	// To openlist 路径前缀, 开启 Regex 时可以使用 $1, ${name} 引用捕获组
+	To string `yaml:"to"`
 
-	// emby2OpenlistArr 根据 Emby2Openlist 转换成路径键值对数组
-	emby2OpenlistArr [][2]string
+	// Regex 是否使用正则表达式匹配
+	Regex bool `yaml:"regex"`
+
+	// IgnoreCase 是否忽略大小写
+	IgnoreCase bool `yaml:"ignore-case"`
+
+	// reg 编译后的正则表达式
+	reg *regexp.Regexp
 }
 
-func (p *Path) Init() error {
-	p.emby2OpenlistArr = make([][2]string, 0, len(p.Emby2Openlist))
-	for _, e2a := range p.Emby2Openlist {
-		arr := strings.Split(e2a, ":")
+// UnmarshalYAML 支持旧版本的 "emby路径:openlist路径" 字符串格式
+func (pm *PathMapping) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		arr := strings.Split(node.Value, ":")
 		if len(arr) != 2 {
-			return fmt.Errorf("path.emby2openlist 配置错误, %s 无法根据 ':' 进行分割", e2a)
+			return fmt.Errorf("%s 无法根据 ':' 进行分割, 路径中包含 ':' 时请使用 from/to 格式配置", node.Value)
+		}
+		pm.From, pm.To = arr[0], arr[1]
+		return nil
+	}
+
+	type rawMapping PathMapping
+	return node.Decode((*rawMapping)(pm))
+}
+
+// Init 校验规则并编译正则表达式
+func (pm *PathMapping) Init() error {
+	if strs.AnyEmpty(pm.From) {
+		return errors.New("from 不能为空")
+	}
+
+	if !pm.Regex {
+		pm.From = urls.TransferSlash(pm.From)
+		return nil
+	}
+
+	expr := pm.From
+	if pm.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	reg, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("正则表达式编译失败: %s, err: %v", pm.From, err)
+	}
+	pm.reg = reg
+	return nil
+}
+
+// Map 使用当前规则映射路径, 规则不匹配时返回 false
+func (pm *PathMapping) Map(embyPath string) (string, bool) {
+	if pm.Regex {
+		loc := pm.reg.FindStringSubmatchIndex(embyPath)
+		if loc == nil {
+			return "", false
+		}
+		mapped := pm.reg.ExpandString(nil, pm.To, embyPath, loc)
+		return embyPath[:loc[0]] + string(mapped) + embyPath[loc[1]:], true
+	}
+
+	if len(embyPath) < len(pm.From) {
+		return "", false
+	}
+	prefix := embyPath[:len(pm.From)]
+	if prefix == pm.From || (pm.IgnoreCase && strings.EqualFold(prefix, pm.From)) {
+		return pm.To + embyPath[len(pm.From):], true
+	}
+	return "", false
+}
+
+// String 规则的字符串描述, 用于日志输出
+func (pm *PathMapping) String() string {
+	s := pm.From + " => " + pm.To
+	var flags []string
+	if pm.Regex {
+		flags = append(flags, "正则")
+	}
+	if pm.IgnoreCase {
+		flags = append(flags, "忽略大小写")
+	}
+	if len(flags) > 0 {
+		s += " (" + strings.Join(flags, ", ") + ")"
+	}
+	return s
+}
+
+func (p *Path) Init() error {
+	for i, pm := range p.Emby2Openlist {
+		if pm == nil {
+			return fmt.Errorf("path.emby2openlist 配置错误, 第 %d 条规则为空", i+1)
+		}
+		if err := pm.Init(); err != nil {
+			return fmt.Errorf("path.emby2openlist 配置错误, 第 %d 条规则: %v", i+1, err)
 		}
-		p.emby2OpenlistArr = append(p.emby2OpenlistArr, [2]string{arr[0], arr[1]})
 	}
 	return nil
 }
 
 // MapEmby2Openlist 将 emby 路径映射成 openlist 路径
 func (p *Path) MapEmby2Openlist(embyPath string) (string, bool) {
-	for _, cfg := range p.emby2OpenlistArr {
-		ep, ap := cfg[0], cfg[1]
-		if strings.HasPrefix(embyPath, ep) {
-			logs.Tip("命中 emby2openlist 路径映射: %s => %s (如命中错误, 请将正确的映射配置前移)", ep, ap)
-			return strings.Replace(embyPath, ep, ap, 1), true
+	res, rule := p.MatchEmby2Openlist(embyPath)
+	return res, rule != nil
+}
+
+// MatchEmby2Openlist 将 emby 路径映射成 openlist 路径, 同时返回命中的规则
+//
+// 规则按配置顺序自上而下匹配, 没有命中任何规则时, 返回的规则为 nil
+func (p *Path) MatchEmby2Openlist(embyPath string) (string, *PathMapping) {
+	for _, pm := range p.Emby2Openlist {
+		if res, ok := pm.Map(embyPath); ok {
+			return res, pm
 		}
 	}
-	return "", false
+	return "", nil
+}
+
+// OpenlistRoots 获取所有非正则映射规则中的 openlist 路径前缀
+func (p *Path) OpenlistRoots() []string {
+	roots := make([]string, 0, len(p.Emby2Openlist))
+	for _, pm := range p.Emby2Openlist {
+		if pm.Regex {
+			continue
+		}
+		roots = append(roots, pm.To)
+	}
+	return roots
 }
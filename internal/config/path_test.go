@@ -0,0 +1,71 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPathMapping(t *testing.T) {
+	content := `
+emby2openlist:
+  - /movie:/电影
+  - from: 'D:\media\tv'
+    to: /电视剧
+  - from: ^/anime/(?P<year>\d{4})/
+    to: /动漫/${year}年/
+    regex: true
+  - from: /Music
+    to: /音乐
+    ignore-case: true
+  - from: ^/DOC/([^/]+)/
+    to: /纪录片/$1-
+    regex: true
+    ignore-case: true
+`
+	var p config.Path
+	if err := yaml.Unmarshal([]byte(content), &p); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"/movie/a.mkv", "/电影/a.mkv", true},
+		{"D:/media/tv/b.mkv", "/电视剧/b.mkv", true},
+		{"/anime/2024/c.mkv", "/动漫/2024年/c.mkv", true},
+		{"/music/d.flac", "/音乐/d.flac", true},
+		{"/doc/nature/e.mkv", "/纪录片/nature-e.mkv", true},
+		{"/Movie/a.mkv", "", false},
+		{"/anime/old/c.mkv", "", false},
+	}
+	for _, c := range cases {
+		got, ok := p.MapEmby2Openlist(c.in)
+		if ok != c.ok || got != c.want {
+			t.Errorf("映射 %s, 期望: (%s, %v), 实际: (%s, %v)", c.in, c.want, c.ok, got, ok)
+		}
+	}
+}
+
+func TestPathMappingInvalid(t *testing.T) {
+	contents := []string{
+		"emby2openlist:\n  - /a:/b:/c\n",
+		"emby2openlist:\n  - from: '[a-'\n    to: /b\n    regex: true\n",
+		"emby2openlist:\n  - to: /b\n",
+	}
+	for _, content := range contents {
+		var p config.Path
+		err := yaml.Unmarshal([]byte(content), &p)
+		if err == nil {
+			err = p.Init()
+		}
+		if err == nil {
+			t.Errorf("期望配置校验失败: %s", content)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultRateLimitMaxWait 默认的限流排队等待时间 (秒)
+const DefaultRateLimitMaxWait = 5
+
+// RateLimit 获取直链的限流配置
+//
+// 避免短时间内大量请求网盘直链, 触发网盘的风控
+type RateLimit struct {
+	// Enable 是否启用限流
+	Enable bool `yaml:"enable"`
+
+	// MaxWait 令牌不足时最长的排队等待时间, 单位: 秒, 超时后返回 429
+	MaxWait int `yaml:"max-wait"`
+
+	// Global 所有请求共用的限流
+	Global Rate `yaml:"global"`
+
+	// PerIp 每个客户端 ip 的限流
+	PerIp Rate `yaml:"per-ip"`
+
+	// PerUser 每个 emby 用户的限流, 以客户端的访问令牌 (api_key) 区分用户
+	PerUser Rate `yaml:"per-user"`
+}
+
+// Rate 令牌桶参数
+type Rate struct {
+	// PerMinute 每分钟生成的令牌数, 为 0 时不限流
+	PerMinute int `yaml:"per-minute"`
+
+	// Burst 令牌桶容量, 即允许的突发请求数, 默认为 10 秒内生成的令牌数
+	Burst int `yaml:"burst"`
+}
+
+func (rl *RateLimit) Init() error {
+	if rl.MaxWait < 0 {
+		return fmt.Errorf("rate-limit.max-wait 配置错误: %d, 值不能小于 0", rl.MaxWait)
+	}
+	if rl.MaxWait == 0 {
+		rl.MaxWait = DefaultRateLimitMaxWait
+	}
+
+	for name, r := range map[string]*Rate{"global": &rl.Global, "per-ip": &rl.PerIp, "per-user": &rl.PerUser} {
+		if err := r.init(); err != nil {
+			return fmt.Errorf("rate-limit.%s 配置错误: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// init 校验参数并设置默认值
+func (r *Rate) init() error {
+	if r.PerMinute < 0 || r.Burst < 0 {
+		return fmt.Errorf("per-minute, burst 不能小于 0")
+	}
+	if r.PerMinute > 0 && r.Burst == 0 {
+		r.Burst = max(1, r.PerMinute/6)
+	}
+	return nil
+}
+
+// MaxWaitDuration 最长的排队等待时间
+func (rl *RateLimit) MaxWaitDuration() time.Duration {
+	return time.Second * time.Duration(rl.MaxWait)
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// WatchInterval 轮询配置文件变更的时间间隔
+const WatchInterval = time.Second * 3
+
+var (
+	// reloadMu 保证同一时刻只有一个重载流程在执行
+	reloadMu sync.Mutex
+
+	// reloadHooks 配置重载成功后需要执行的回调
+	reloadHooks []func()
+)
+
+// OnReload 注册配置重载成功后的回调函数
+//
+// 回调按注册顺序执行, 执行时全局配置 C 已经是新的配置对象
+func OnReload(fn func()) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, fn)
+}
+
+// Reload 重新读取配置文件并替换全局配置
+//
+// 若新的配置文件无法解析或校验失败, 会返回错误并继续使用旧的配置
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if filePath == "" {
+		return fmt.Errorf("配置文件尚未初始化")
+	}
+
+	c, err := load(filePath)
+	if err != nil {
+		return err
+	}
+
+	old := C()
+	setCurrent(c)
+	if old.Ssl.Enable != c.Ssl.Enable || old.Ssl.SinglePort != c.Ssl.SinglePort {
+		logs.Warn("ssl 监听模式的变更需要重启程序后才能生效")
+	}
+	if c.Ssl.NeedSelfSigned() {
+		logs.Warn("证书文件不存在, 自签名证书需要重启程序后才会生成")
+	}
+	if old.Server.Http2 != c.Server.Http2 || old.Server.H2c != c.Server.H2c {
+		logs.Warn("server.http2, server.h2c 的变更需要重启程序后才能生效")
+	}
+	if !slices.Equal(old.Server.Listen.Http, c.Server.Listen.Http) ||
+		!slices.Equal(old.Server.Listen.Https, c.Server.Listen.Https) ||
+		old.Server.Pprof != c.Server.Pprof {
+		logs.Warn("server.listen, server.pprof 的变更需要重启程序后才能生效")
+	}
+	if old.Cache.Disk.Enable != c.Cache.Disk.Enable || old.Cache.Disk.DirPath() != c.Cache.Disk.DirPath() {
+		logs.Warn("cache.disk.enable, cache.disk.dir 的变更需要重启程序后才能生效")
+	}
+	if old.Cache.Backend != c.Cache.Backend || old.Cache.Redis != c.Cache.Redis {
+		logs.Warn("cache.backend, cache.redis 的变更需要重启程序后才能生效")
+	}
+
+	for _, fn := range reloadHooks {
+		fn()
+	}
+	return nil
+}
+
+// Watch 监听配置文件变更, 在文件被修改或收到 SIGHUP 信号时自动重载配置
+//
+// 该函数会阻塞当前 goroutine, 需要异步调用
+func Watch() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	lastMod := modTime()
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sigChan:
+			logs.Info("收到 SIGHUP 信号, 正在重载配置文件...")
+		case <-ticker.C:
+			mod := modTime()
+			if mod.IsZero() || mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+			logs.Info("检测到配置文件发生变更, 正在重载配置文件...")
+		}
+
+		if err := Reload(); err != nil {
+			logs.Error("配置重载失败, 继续使用旧配置: %v", err)
+			continue
+		}
+		logs.Success("配置重载成功")
+	}
+}
+
+// modTime 获取配置文件的最后修改时间, 获取失败时返回零值
+func modTime() time.Time {
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return time.Time{}
+	}
+	return stat.ModTime()
+}
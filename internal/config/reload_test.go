@@ -0,0 +1,102 @@
+package config_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestReload(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	write := func(content string) {
+		if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("emby:\n  host: http://127.0.0.1:8096\n")
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	hookCalled := 0
+	config.OnReload(func() { hookCalled++ })
+
+	// 合法的配置变更
+	write("emby:\n  host: http://127.0.0.1:18096\n")
+	if err := config.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if config.C().Emby.Host != "http://127.0.0.1:18096" {
+		t.Fatalf("配置未更新: %s", config.C().Emby.Host)
+	}
+	if hookCalled != 1 {
+		t.Fatalf("回调执行次数异常: %d", hookCalled)
+	}
+
+	// 非法的配置变更, 需保留旧配置
+	write("emby:\n  host: \"\"\n")
+	if err := config.Reload(); err == nil {
+		t.Fatal("期望重载失败")
+	}
+	if config.C().Emby.Host != "http://127.0.0.1:18096" {
+		t.Fatalf("重载失败后配置被修改: %s", config.C().Emby.Host)
+	}
+	if hookCalled != 1 {
+		t.Fatalf("重载失败后不应执行回调: %d", hookCalled)
+	}
+}
+
+// TestReloadConcurrentRead 重载配置的同时并发读取配置, 需要配合 -race 运行
+func TestReloadConcurrentRead(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	write := func(port int) {
+		content := fmt.Sprintf("emby:\n  host: http://127.0.0.1:%d\n", port)
+		if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(8096)
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if config.C().Emby.Host == "" {
+					t.Error("读取到未初始化的配置")
+					return
+				}
+				config.ServerInternalRequestHost()
+			}
+		}()
+	}
+
+	for i := range 20 {
+		write(18096 + i)
+		if err := config.Reload(); err != nil {
+			t.Error(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if host := config.C().Emby.Host; host != "http://127.0.0.1:18115" {
+		t.Fatalf("配置未更新: %s", host)
+	}
+}
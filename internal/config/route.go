@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// 自定义路由的处理动作
+const (
+	RouteActionOrigin   = "origin"   // 代理回源
+	RouteActionReject   = "reject"   // 拒绝请求
+	RouteActionRedirect = "redirect" // 重定向到指定地址
+	RouteActionBuiltin  = "builtin"  // 使用内置的处理器
+)
+
+// 自定义路由相对于内置路由的位置
+const (
+	RoutePositionBefore = "before" // 优先于内置路由匹配
+	RoutePositionAfter  = "after"  // 内置路由都不匹配时, 在兜底回源之前匹配
+)
+
+// Route 自定义路由规则
+type Route struct {
+	// Name 规则名称, 用于日志输出以及统计指标的 route 标签
+	Name string `yaml:"name"`
+
+	// Pattern 匹配请求 uri 的正则表达式
+	Pattern string `yaml:"pattern"`
+
+	// Methods 匹配的请求方法, 为空时匹配所有方法
+	Methods []string `yaml:"methods"`
+
+	// UA 匹配 User-Agent 的正则表达式, 为空时不校验
+	UA string `yaml:"ua"`
+
+	// Headers 匹配请求头的正则表达式, 请求头名称 => 正则表达式, 需要全部匹配
+	Headers map[string]string `yaml:"headers"`
+
+	// Action 处理动作: origin, reject, redirect, builtin
+	Action string `yaml:"action"`
+
+	// Status 响应状态码, reject 默认 403, redirect 默认 302
+	Status int `yaml:"status"`
+
+	// Target 重定向地址模板, 可使用 ${uri} 引用原始请求 uri, 使用 $1, ${name} 引用 pattern 的捕获组
+	Target string `yaml:"target"`
+
+	// Handler 内置处理器名称, action 为 builtin 时必填
+	Handler string `yaml:"handler"`
+
+	// Position 相对于内置路由的位置: before, after, 默认为 before
+	Position string `yaml:"position"`
+
+	// uaReg 编译后的 UA 正则表达式
+	uaReg *regexp.Regexp
+
+	// headerRegs 编译后的请求头正则表达式
+	headerRegs map[string]*regexp.Regexp
+}
+
+// Routes 自定义路由规则列表, 按配置顺序匹配
+type Routes []*Route
+
+func (rs Routes) Init() error {
+	for i, r := range rs {
+		if r == nil {
+			return fmt.Errorf("routes 配置错误, 第 %d 条规则为空", i+1)
+		}
+		if err := r.Init(); err != nil {
+			return fmt.Errorf("routes 配置错误, 第 %d 条规则: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Init 校验规则并编译正则表达式
+func (r *Route) Init() error {
+	if strs.AnyEmpty(r.Pattern) {
+		return fmt.Errorf("pattern 不能为空")
+	}
+	if _, err := regexp.Compile(r.Pattern); err != nil {
+		return fmt.Errorf("pattern 编译失败: %v", err)
+	}
+	if r.Name == "" {
+		r.Name = r.Pattern
+	}
+
+	for i, m := range r.Methods {
+		r.Methods[i] = strings.ToUpper(strings.TrimSpace(m))
+	}
+
+	if r.UA != "" {
+		reg, err := regexp.Compile(r.UA)
+		if err != nil {
+			return fmt.Errorf("ua 编译失败: %v", err)
+		}
+		r.uaReg = reg
+	}
+
+	r.headerRegs = make(map[string]*regexp.Regexp, len(r.Headers))
+	for k, v := range r.Headers {
+		reg, err := regexp.Compile(v)
+		if err != nil {
+			return fmt.Errorf("headers.%s 编译失败: %v", k, err)
+		}
+		r.headerRegs[k] = reg
+	}
+
+	switch r.Action {
+	case RouteActionOrigin:
+	case RouteActionReject:
+		if r.Status == 0 {
+			r.Status = http.StatusForbidden
+		}
+		if r.Status < 400 || r.Status > 599 {
+			return fmt.Errorf("status 配置错误: %d, reject 只能使用 4xx, 5xx 状态码", r.Status)
+		}
+	case RouteActionRedirect:
+		if strs.AnyEmpty(r.Target) {
+			return fmt.Errorf("action 为 redirect 时, target 不能为空")
+		}
+		if r.Status == 0 {
+			r.Status = http.StatusFound
+		}
+		if r.Status < 300 || r.Status > 399 {
+			return fmt.Errorf("status 配置错误: %d, redirect 只能使用 3xx 状态码", r.Status)
+		}
+	case RouteActionBuiltin:
+		if strs.AnyEmpty(r.Handler) {
+			return fmt.Errorf("action 为 builtin 时, handler 不能为空")
+		}
+		if !slices.Contains(constant.BuiltinRoutes, r.Handler) {
+			return fmt.Errorf("handler 配置错误: %s, 可选值: %s", r.Handler, strings.Join(constant.BuiltinRoutes, ", "))
+		}
+	default:
+		return fmt.Errorf("action 配置错误: %s, 可选值: origin, reject, redirect, builtin", r.Action)
+	}
+
+	switch r.Position {
+	case "":
+		r.Position = RoutePositionBefore
+	case RoutePositionBefore, RoutePositionAfter:
+	default:
+		return fmt.Errorf("position 配置错误: %s, 可选值: before, after", r.Position)
+	}
+	return nil
+}
+
+// Match 判断请求方法以及请求头是否满足规则的附加条件
+//
+// 请求 uri 的匹配由路由模块负责
+func (r *Route) Match(method string, header http.Header) bool {
+	if len(r.Methods) > 0 && !slices.Contains(r.Methods, strings.ToUpper(method)) {
+		return false
+	}
+	if r.uaReg != nil && !r.uaReg.MatchString(header.Get("User-Agent")) {
+		return false
+	}
+	for k, reg := range r.headerRegs {
+		if !reg.MatchString(header.Get(k)) {
+			return false
+		}
+	}
+	return true
+}
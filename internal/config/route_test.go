@@ -0,0 +1,78 @@
+package config_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestRouteInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   config.Route
+		wantErr bool
+	}{
+		{"回源", config.Route{Pattern: `^/emby/items`, Action: "origin"}, false},
+		{"拒绝默认状态码", config.Route{Pattern: `^/emby/items`, Action: "reject"}, false},
+		{"拒绝状态码错误", config.Route{Pattern: `^/emby/items`, Action: "reject", Status: 200}, true},
+		{"重定向", config.Route{Pattern: `^/old/(.*)`, Action: "redirect", Target: "/new/$1"}, false},
+		{"重定向缺少地址", config.Route{Pattern: `^/old/(.*)`, Action: "redirect"}, true},
+		{"内置处理器", config.Route{Pattern: `^/stream`, Action: "builtin", Handler: "resource-stream"}, false},
+		{"内置处理器缺少名称", config.Route{Pattern: `^/stream`, Action: "builtin"}, true},
+		{"内置处理器不存在", config.Route{Pattern: `^/stream`, Action: "builtin", Handler: "resource-streams"}, true},
+		{"未知动作", config.Route{Pattern: `^/stream`, Action: "drop"}, true},
+		{"正则错误", config.Route{Pattern: `(`, Action: "origin"}, true},
+		{"UA 正则错误", config.Route{Pattern: `^/`, UA: `(`, Action: "origin"}, true},
+		{"位置错误", config.Route{Pattern: `^/`, Action: "origin", Position: "middle"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.route.Init()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Init() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRouteMatch(t *testing.T) {
+	r := config.Route{
+		Pattern: `^/emby/videos`,
+		Methods: []string{"get"},
+		UA:      `(?i)infuse`,
+		Headers: map[string]string{"X-Emby-Client": `^Infuse`},
+		Action:  "origin",
+	}
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Position != config.RoutePositionBefore {
+		t.Errorf("默认位置期望 before, 实际: %s", r.Position)
+	}
+
+	header := func(ua, client string) http.Header {
+		h := make(http.Header)
+		h.Set("User-Agent", ua)
+		h.Set("X-Emby-Client", client)
+		return h
+	}
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		want   bool
+	}{
+		{"全部匹配", http.MethodGet, header("Infuse-Direct/8.0", "Infuse-Direct"), true},
+		{"方法不匹配", http.MethodPost, header("Infuse-Direct/8.0", "Infuse-Direct"), false},
+		{"UA 不匹配", http.MethodGet, header("VLC/3.0", "Infuse-Direct"), false},
+		{"请求头不匹配", http.MethodGet, header("Infuse-Direct/8.0", "Emby Web"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Match(tt.method, tt.header); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
+)
+
+// DefaultShutdownTimeout 默认的优雅退出超时时间 (秒)
+const DefaultShutdownTimeout = 30
+
+// Server 服务相关配置
+type Server struct {
+	// ShutdownTimeout 收到退出信号后, 等待处理中的请求以及后台任务完成的最长时间, 单位: 秒
+	ShutdownTimeout int `yaml:"shutdown-timeout"`
+
+	// Http2 是否在 https 服务上启用 HTTP/2
+	Http2 bool `yaml:"http2"`
+
+	// H2c 是否在 http 服务上启用明文 HTTP/2 (h2c, 仅支持 prior knowledge 方式)
+	H2c bool `yaml:"h2c"`
+
+	// Listen 服务监听地址
+	Listen Listen `yaml:"listen"`
+
+	// Pprof 性能分析服务监听地址, 为空时不启动
+	Pprof string `yaml:"pprof"`
+}
+
+// Listen 服务监听地址, 每种协议都可以配置多个地址
+//
+// 未配置时使用命令行参数指定的端口, 监听 0.0.0.0
+type Listen struct {
+	// Http http 服务监听地址
+	Http []string `yaml:"http"`
+
+	// Https https 服务监听地址
+	Https []string `yaml:"https"`
+
+	// http, https 解析后的监听地址
+	http, https []webport.Listener
+}
+
+func (s *Server) Init() error {
+	if s.ShutdownTimeout < 0 {
+		return fmt.Errorf("server.shutdown-timeout 配置错误: %d, 值不能小于 0", s.ShutdownTimeout)
+	}
+	if s.ShutdownTimeout == 0 {
+		s.ShutdownTimeout = DefaultShutdownTimeout
+	}
+
+	var err error
+	if s.Listen.http, err = parseListeners(s.Listen.Http); err != nil {
+		return fmt.Errorf("server.listen.http 配置错误: %v", err)
+	}
+	if s.Listen.https, err = parseListeners(s.Listen.Https); err != nil {
+		return fmt.Errorf("server.listen.https 配置错误: %v", err)
+	}
+
+	if s.Pprof != "" {
+		if _, err := webport.Parse(s.Pprof); err != nil {
+			return fmt.Errorf("server.pprof 配置错误: %v", err)
+		}
+	}
+	return nil
+}
+
+// parseListeners 解析监听地址列表, 不允许出现重复的地址
+func parseListeners(addrs []string) ([]webport.Listener, error) {
+	res := make([]webport.Listener, 0, len(addrs))
+	visited := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		l, err := webport.Parse(addr)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := visited[l.String()]; ok {
+			return nil, fmt.Errorf("监听地址重复: %s", l)
+		}
+		visited[l.String()] = struct{}{}
+		res = append(res, l)
+	}
+	return res, nil
+}
+
+// HttpListeners http 服务的监听地址
+func (s *Server) HttpListeners() []webport.Listener {
+	if len(s.Listen.http) == 0 {
+		return []webport.Listener{webport.Default(webport.HTTP)}
+	}
+	return s.Listen.http
+}
+
+// HttpsListeners https 服务的监听地址
+func (s *Server) HttpsListeners() []webport.Listener {
+	if len(s.Listen.https) == 0 {
+		return []webport.Listener{webport.Default(webport.HTTPS)}
+	}
+	return s.Listen.https
+}
+
+// ShutdownTimeoutDuration 优雅退出超时时间
+func (s *Server) ShutdownTimeoutDuration() time.Duration {
+	return time.Second * time.Duration(s.ShutdownTimeout)
+}
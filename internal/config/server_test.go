@@ -0,0 +1,56 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
+)
+
+func TestServerListen(t *testing.T) {
+	s := config.Server{Listen: config.Listen{
+		Http: []string{":8095", "[::]:8095", "192.168.1.2:18095", "unix:/run/ge2o.sock"},
+	}}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		str, port, localHost string
+	}{
+		{":8095", "8095", "127.0.0.1:8095"},
+		{"[::]:8095", "8095", "[::1]:8095"},
+		{"192.168.1.2:18095", "18095", "192.168.1.2:18095"},
+		{"unix:/run/ge2o.sock", "", ""},
+	}
+	ls := s.HttpListeners()
+	if len(ls) != len(want) {
+		t.Fatalf("监听地址数量异常: %d", len(ls))
+	}
+	for i, w := range want {
+		l := ls[i]
+		if l.String() != w.str || l.Port != w.port || l.LocalHost() != w.localHost {
+			t.Errorf("第 %d 个监听地址解析异常: %+v, localHost: %s", i+1, l, l.LocalHost())
+		}
+	}
+
+	// 未配置时使用命令行参数指定的端口
+	if ls := s.HttpsListeners(); len(ls) != 1 || ls[0] != webport.Default(webport.HTTPS) {
+		t.Errorf("默认监听地址异常: %+v", ls)
+	}
+}
+
+func TestServerListenInvalid(t *testing.T) {
+	cases := []config.Server{
+		{Listen: config.Listen{Http: []string{"8095"}}},
+		{Listen: config.Listen{Http: []string{":0"}}},
+		{Listen: config.Listen{Https: []string{"unix:"}}},
+		{Listen: config.Listen{Http: []string{":8095", "0.0.0.0:8095", ":8095"}}},
+		{Pprof: "localhost"},
+	}
+	for i, s := range cases {
+		if err := s.Init(); err == nil {
+			t.Errorf("第 %d 个用例期望报错", i+1)
+		}
+	}
+}
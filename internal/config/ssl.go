@@ -1,22 +1,42 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"math/big"
+	"net"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 )
 
-// SslDir ssl 证书存放目录名称
-const SslDir = "ssl"
+const (
+	// SslDir ssl 证书存放目录名称
+	SslDir = "ssl"
+
+	// SelfSignedCrt, SelfSignedKey 自签名证书未配置文件名时使用的默认文件名
+	SelfSignedCrt = "self-signed.crt"
+	SelfSignedKey = "self-signed.key"
+
+	// SelfSignedValidity 自签名证书的有效期
+	SelfSignedValidity = time.Hour * 24 * 365 * 10
+)
 
 type Ssl struct {
 	Enable     bool   `yaml:"enable"`      // 是否启用
 	SinglePort bool   `yaml:"single-port"` // 是否使用单一端口
 	Key        string `yaml:"key"`         // 服务器私钥名称
 	Crt        string `yaml:"crt"`         // 证书名称
+	SelfSigned bool   `yaml:"self-signed"` // 证书不存在时, 是否自动生成自签名证书
 }
 
 func (s *Ssl) Init() error {
@@ -24,8 +44,13 @@ func (s *Ssl) Init() error {
 		return nil
 	}
 
-	if err := initSslDir(); err != nil {
-		return fmt.Errorf("初始化 ssl 目录失败: %v", err)
+	if s.SelfSigned {
+		if s.Crt == "" {
+			s.Crt = SelfSignedCrt
+		}
+		if s.Key == "" {
+			s.Key = SelfSignedKey
+		}
 	}
 
 	// 非空校验
@@ -36,6 +61,11 @@ func (s *Ssl) Init() error {
 		return errors.New("ssl.key 配置不能为空")
 	}
 
+	// 证书密钥都不存在时, 由 EnsureCert 在服务启动时生成自签名证书
+	if s.NeedSelfSigned() {
+		return nil
+	}
+
 	// 判断证书密钥是否存在
 	if stat, err := os.Stat(s.CrtPath()); err != nil || stat.IsDir() {
 		return fmt.Errorf("检测不到证书文件, err: %v", err)
@@ -47,6 +77,31 @@ func (s *Ssl) Init() error {
 	return nil
 }
 
+// NeedSelfSigned 判断是否需要生成自签名证书
+//
+// 开启了自签名, 并且证书和密钥文件都不存在时返回 true
+func (s *Ssl) NeedSelfSigned() bool {
+	return s.Enable && s.SelfSigned && !fileExists(s.CrtPath()) && !fileExists(s.KeyPath())
+}
+
+// EnsureCert 在服务启动时调用, 需要时生成自签名证书
+//
+// 配置校验 (包括 -check 模式以及配置重载) 不会写入任何文件
+func (s *Ssl) EnsureCert() error {
+	if !s.NeedSelfSigned() {
+		return nil
+	}
+
+	if err := initSslDir(); err != nil {
+		return fmt.Errorf("初始化 ssl 目录失败: %v", err)
+	}
+	if err := generateSelfSigned(s.CrtPath(), s.KeyPath()); err != nil {
+		return fmt.Errorf("生成自签名证书失败: %v", err)
+	}
+	logs.Success("已生成自签名证书: %s", s.CrtPath())
+	return nil
+}
+
 // CrtPath 获取 cert 证书的绝对路径
 func (s *Ssl) CrtPath() string {
 	return filepath.Join(BasePath, SslDir, s.Crt)
@@ -69,3 +124,66 @@ func initSslDir() error {
 
 	return os.MkdirAll(absPath, os.ModeDir|os.ModePerm)
 }
+
+// fileExists 判断路径是否为已存在的文件
+func fileExists(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && !stat.IsDir()
+}
+
+// generateSelfSigned 生成自签名证书, 并写入到指定路径中
+//
+// 证书包含 localhost, 主机名以及本机所有网卡的 ip 地址, 方便在局域网内直接使用
+func generateSelfSigned(crtPath, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("生成私钥失败: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("生成证书序列号失败: %v", err)
+	}
+
+	now := time.Now()
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "go-emby2openlist", Organization: []string{"go-emby2openlist"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(SelfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" && hostname != "localhost" {
+		tmpl.DNSNames = append(tmpl.DNSNames, hostname)
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ipNet.IP)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("生成证书失败: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("序列化私钥失败: %v", err)
+	}
+
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return fmt.Errorf("写入私钥失败: %v", err)
+	}
+	if err := os.WriteFile(crtPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("写入证书失败: %v", err)
+	}
+	return nil
+}
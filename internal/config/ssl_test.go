@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"crypto/tls"
+	"os"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestSslSelfSigned(t *testing.T) {
+	old := config.BasePath
+	config.BasePath = t.TempDir()
+	defer func() { config.BasePath = old }()
+
+	s := config.Ssl{Enable: true, SelfSigned: true}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Crt != config.SelfSignedCrt || s.Key != config.SelfSignedKey {
+		t.Fatalf("默认文件名异常: %s, %s", s.Crt, s.Key)
+	}
+
+	// 校验配置时不写入任何文件
+	if entries, err := os.ReadDir(config.BasePath); err != nil || len(entries) != 0 {
+		t.Fatalf("校验配置时不应写入文件: %v, err: %v", entries, err)
+	}
+	if !s.NeedSelfSigned() {
+		t.Fatal("期望需要生成自签名证书")
+	}
+
+	if err := s.EnsureCert(); err != nil {
+		t.Fatal(err)
+	}
+	if s.NeedSelfSigned() {
+		t.Fatal("自签名证书生成后不应再次生成")
+	}
+	if _, err := tls.LoadX509KeyPair(s.CrtPath(), s.KeyPath()); err != nil {
+		t.Fatalf("自签名证书不可用: %v", err)
+	}
+
+	// 证书已存在时, 不重复生成
+	before, err := os.ReadFile(s.CrtPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnsureCert(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(s.CrtPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Fatal("已存在的证书被覆盖")
+	}
+
+	// 未开启自签名时, 证书不存在需要报错
+	s = config.Ssl{Enable: true, Crt: "none.crt", Key: "none.key"}
+	if err := s.Init(); err == nil {
+		t.Fatal("期望证书不存在时报错")
+	}
+}
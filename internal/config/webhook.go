@@ -0,0 +1,16 @@
+package config
+
+// Webhook emby 通知接收配置
+//
+// 收到媒体库变更通知后, 清理相关条目的缓存
+type Webhook struct {
+	// Enable 是否启用通知接收接口
+	Enable bool `yaml:"enable"`
+
+	// Secret 共享密钥, 不为空时, 通知请求需要携带 secret 参数或 X-Ge2o-Webhook-Secret 请求头
+	Secret string `yaml:"secret"`
+}
+
+func (w *Webhook) Init() error {
+	return nil
+}
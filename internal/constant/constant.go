@@ -42,13 +42,39 @@ const (
 	Reg_IndexHtml   = `(?i)^/web/index\.html`
 	Route_CustomJs  = `/ge2o/custom.js`
 	Route_CustomCss = `/ge2o/custom.css`
+	Reg_Admin       = `(?i)^/ge2o/admin/([^?]*)`
+	Reg_Metrics     = `(?i)^/ge2o/metrics($|\?)`
+	Reg_Webhook     = `(?i)^/ge2o/webhook($|\?)`
 
 	Reg_All = `.*`
 )
 
 const (
 	RouteSubMatchGinKey = "routeSubMatches" // 路由匹配成功时, 会将匹配的正则结果存放到 Gin 上下文
+	EmbyUpstreamGinKey  = "embyUpstream"    // 当前请求匹配到的 emby 上游, 存放到 Gin 上下文
+	RequestIdGinKey     = "requestId"       // 当前请求的 id, 存放到 Gin 上下文
+	ItemIdGinKey        = "itemId"          // 当前请求解析出的 emby item id, 存放到 Gin 上下文
+	CacheHitGinKey      = "cacheHit"        // 当前请求是否命中了缓存, 存放到 Gin 上下文
+	CacheRouteGinKey    = "cacheRoute"      // 当前请求匹配到的自定义缓存规则, 存放到 Gin 上下文
+
+	HeaderEmbyUpstream  = "X-Ge2o-Upstream"       // 服务内部自请求时, 指定 emby 上游名称的请求头
+	HeaderInternalToken = "X-Ge2o-Internal-Token" // 服务内部自请求的凭证, 携带合法凭证时 HeaderEmbyUpstream 才会生效
+	HeaderRequestId     = "X-Request-Id"          // 请求 id 响应头, 客户端或反向代理传入合法的值时会沿用
 
 	CustomJsDirName  = "custom-js"  // 自定义脚本存放目录
 	CustomCssDirName = "custom-css" // 自定义样式存放目录
 )
+
+// BuiltinRoutes 内置路由规则的名称, 按匹配顺序排列
+//
+// 自定义路由的 builtin 动作只能引用列表中的名称
+var BuiltinRoutes = []string{
+	"admin", "metrics", "webhook", "socket", "playback-info",
+	"playing-stopped", "playing-progress",
+	"user-items", "user-episode-items", "user-items-random-resort", "user-items-random-with-limit", "user-latest-items",
+	"show-episodes", "video-subtitles",
+	"resource-stream", "resource-master", "resource-main", "resource-original",
+	"proxy-playlist", "proxy-ts", "proxy-subtitle",
+	"item-download", "item-sync-download", "images", "video-mod-web-defined",
+	"index-html", "custom-js", "custom-css", "root", "origin",
+}
@@ -0,0 +1,201 @@
+package check
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/redis"
+)
+
+// Item 检查项
+type Item struct {
+	// Name 检查项名称
+	Name string
+
+	// Run 执行检查, 返回检查通过时的附加信息
+	Run func() (string, error)
+}
+
+// Result 检查结果
+type Result struct {
+	Name string // 检查项名称
+	Msg  string // 附加信息
+	Err  error  // 检查失败原因, 为 nil 表示检查通过
+}
+
+// Items 根据当前配置生成所有的检查项
+//
+// 调用前需要保证配置已经成功初始化
+func Items() []Item {
+	var items []Item
+	var roots []string
+	for _, e := range config.C().AllEmby() {
+		items = append(items,
+			Item{
+				Name: fmt.Sprintf("emby [%s] 公共信息接口", e.Name),
+				Run:  func() (string, error) { return checkEmbyInfo(e.Host) },
+			},
+			Item{
+				Name: fmt.Sprintf("emby [%s] 鉴权接口连通性", e.Name),
+				Run:  func() (string, error) { return checkEmbyAuthReachable(e.Host) },
+			},
+		)
+		roots = append(roots, e.Path.OpenlistRoots()...)
+	}
+
+	if ltg := config.C().Openlist.LocalTreeGen; ltg.Enable {
+		roots = append(roots, ltg.ScanPrefixes...)
+	}
+	if len(roots) == 0 {
+		// 没有任何路径配置时, 至少校验一次 openlist 令牌
+		roots = append(roots, "/")
+	}
+	visited := make(map[string]struct{})
+	for _, root := range roots {
+		if _, ok := visited[root]; ok {
+			continue
+		}
+		visited[root] = struct{}{}
+		items = append(items, Item{
+			Name: fmt.Sprintf("openlist 路径 [%s]", root),
+			Run:  func() (string, error) { return checkOpenlistPath(root) },
+		})
+	}
+
+	if config.C().Cache.Backend == config.CacheBackendRedis {
+		items = append(items, Item{Name: "redis 缓存", Run: checkRedis})
+	}
+
+	if config.C().Ssl.Enable {
+		items = append(items, Item{Name: "ssl 证书", Run: checkSsl})
+	}
+	return items
+}
+
+// Run 依次执行所有的检查项
+func Run() []Result {
+	items := Items()
+	res := make([]Result, 0, len(items))
+	for _, item := range items {
+		msg, err := item.Run()
+		res = append(res, Result{Name: item.Name, Msg: msg, Err: err})
+	}
+	return res
+}
+
+// Report 将检查结果输出到 w 中, 全部检查通过时返回 true
+func Report(w io.Writer, res []Result) bool {
+	failed := 0
+	for _, r := range res {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintln(w, colors.ToRed(fmt.Sprintf("[失败] %s: %v", r.Name, r.Err)))
+			continue
+		}
+		line := "[通过] " + r.Name
+		if r.Msg != "" {
+			line += ": " + r.Msg
+		}
+		fmt.Fprintln(w, colors.ToGreen(line))
+	}
+
+	summary := fmt.Sprintf("检查完成, 共 %d 项, 通过 %d 项, 失败 %d 项", len(res), len(res)-failed, failed)
+	if failed > 0 {
+		fmt.Fprintln(w, colors.ToRed(summary))
+		return false
+	}
+	fmt.Fprintln(w, colors.ToGreen(summary))
+	return true
+}
+
+// checkEmbyInfo 请求 emby 的公共信息接口, 校验 emby 上游地址是否可用
+func checkEmbyInfo(origin string) (string, error) {
+	resp, err := https.Get(origin + "/emby/System/Info/Public").Do()
+	if err != nil {
+		return "", fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("响应状态异常: %s", resp.Status)
+	}
+
+	var info struct {
+		ServerName string `json:"ServerName"`
+		Version    string `json:"Version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("响应解析失败, 请确认上游地址是否指向 emby 服务: %v", err)
+	}
+	return fmt.Sprintf("%s (版本: %s)", info.ServerName, info.Version), nil
+}
+
+// checkEmbyAuthReachable 请求 emby 的鉴权接口, 校验接口是否可以访问
+//
+// 只校验连通性, 不校验 api_key 是否有效, 不携带 api_key 请求时 emby 返回 401 同样视为正常
+func checkEmbyAuthReachable(origin string) (string, error) {
+	resp, err := https.Get(origin + emby.AuthUri).Do()
+	if err != nil {
+		return "", fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusUnauthorized:
+		return fmt.Sprintf("响应状态: %s", resp.Status), nil
+	default:
+		return "", fmt.Errorf("响应状态异常: %s", resp.Status)
+	}
+}
+
+// checkOpenlistPath 请求 openlist 的列表接口, 校验令牌以及路径是否有效
+func checkOpenlistPath(path string) (string, error) {
+	res := openlist.FetchFsList(context.Background(), path, nil)
+	if res.Code != http.StatusOK {
+		return "", fmt.Errorf("%s", res.Msg)
+	}
+	return fmt.Sprintf("共 %d 个子项", res.Data.Total), nil
+}
+
+// checkSsl 校验 ssl 证书与私钥是否匹配以及证书是否在有效期内
+func checkSsl() (string, error) {
+	ssl := config.C().Ssl
+	if ssl.NeedSelfSigned() {
+		return "证书不存在, 启动服务时会自动生成自签名证书", nil
+	}
+	cert, err := tls.LoadX509KeyPair(ssl.CrtPath(), ssl.KeyPath())
+	if err != nil {
+		return "", fmt.Errorf("证书与私钥不匹配: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return "", fmt.Errorf("证书解析失败: %v", err)
+	}
+	now := time.Now()
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return "", fmt.Errorf("证书不在有效期内: %s ~ %s", leaf.NotBefore.Format(time.DateTime), leaf.NotAfter.Format(time.DateTime))
+	}
+	return fmt.Sprintf("有效期至 %s", leaf.NotAfter.Format(time.DateTime)), nil
+}
+
+// checkRedis 检查 redis 缓存是否可以连接
+func checkRedis() (string, error) {
+	cfg := config.C().Cache.Redis
+	client := redis.NewClient(redis.Options{Addr: cfg.Addr, Password: cfg.Password, DB: cfg.DB})
+	defer client.Close()
+	if err := client.Ping(context.Background()); err != nil {
+		return "", err
+	}
+	return cfg.Addr, nil
+}
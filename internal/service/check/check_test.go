@@ -0,0 +1,127 @@
+package check_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/check"
+)
+
+// newEmbyStub 模拟 emby 服务
+func newEmbyStub() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/emby/System/Info/Public", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ServerName":"stub","Version":"4.8.0.0"}`))
+	})
+	mux.HandleFunc("/emby/Auth/Keys", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Access token is invalid or expired."))
+	})
+	return httptest.NewServer(mux)
+}
+
+// newOpenlistStub 模拟 openlist 服务, 只有 validPaths 中的路径可以正常列出
+func newOpenlistStub(token string, validPaths ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != token {
+			w.Write([]byte(`{"code":401,"message":"token is invalidated"}`))
+			return
+		}
+		var body struct {
+			Path string `json:"path"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, p := range validPaths {
+			if p == body.Path {
+				w.Write([]byte(`{"code":200,"message":"success","data":{"total":1,"content":[]}}`))
+				return
+			}
+		}
+		w.Write([]byte(`{"code":500,"message":"object not found"}`))
+	}))
+}
+
+func initConfig(t *testing.T, embyHost, openlistHost string) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := fmt.Sprintf(`emby:
+  host: %s
+openlist:
+  host: %s
+  token: stub-token
+path:
+  emby2openlist:
+    - /movie:/电影
+    - /tv:/电视剧
+`, embyHost, openlistHost)
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunPass(t *testing.T) {
+	es := newEmbyStub()
+	defer es.Close()
+	ols := newOpenlistStub("stub-token", "/电影", "/电视剧")
+	defer ols.Close()
+	initConfig(t, es.URL, ols.URL)
+
+	var buf bytes.Buffer
+	if !check.Report(&buf, check.Run()) {
+		t.Fatalf("期望检查通过, 检查报告:\n%s", buf.String())
+	}
+}
+
+func TestRunFail(t *testing.T) {
+	es := newEmbyStub()
+	defer es.Close()
+	ols := newOpenlistStub("stub-token", "/电影")
+	defer ols.Close()
+	initConfig(t, es.URL, ols.URL)
+
+	res := check.Run()
+	var buf bytes.Buffer
+	if check.Report(&buf, res) {
+		t.Fatalf("期望检查失败, 检查报告:\n%s", buf.String())
+	}
+
+	failed := 0
+	for _, r := range res {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("期望 1 项检查失败, 实际: %d, 检查报告:\n%s", failed, buf.String())
+	}
+}
+
+func TestRunInvalidToken(t *testing.T) {
+	es := newEmbyStub()
+	defer es.Close()
+	ols := newOpenlistStub("other-token", "/电影", "/电视剧")
+	defer ols.Close()
+	initConfig(t, es.URL, ols.URL)
+
+	for _, r := range check.Run() {
+		if strings.HasPrefix(r.Name, "emby") {
+			if r.Err != nil {
+				t.Fatalf("%s 检查失败: %v", r.Name, r.Err)
+			}
+			continue
+		}
+		if r.Err == nil {
+			t.Fatalf("%s 期望检查失败", r.Name)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package emby
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/model"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
@@ -19,7 +19,7 @@ import (
 // 如果请求是失败的响应, 会直接返回客户端, 并在第二个参数中返回 false
 func proxyAndSetRespHeader(c *gin.Context) (model.HttpRes[*jsons.Item], bool) {
 	c.Request.Header.Del("Accept-Encoding")
-	res, respHeader := RawFetch(c.Request.URL.String(), c.Request.Method, c.Request.Header, c.Request.Body)
+	res, respHeader := RawFetch(c.Request.Context(), Upstream(c).Host, c.Request.URL.String(), c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		checkErr(c, errors.New(res.Msg))
 		return res, false
@@ -29,13 +29,17 @@ func proxyAndSetRespHeader(c *gin.Context) (model.HttpRes[*jsons.Item], bool) {
 }
 
 // Fetch 请求 emby api 接口, 使用 map 请求体
-func Fetch(uri, method string, header http.Header, body map[string]any) (model.HttpRes[*jsons.Item], http.Header) {
-	return RawFetch(uri, method, header, https.MapBody(body))
+//
+// origin 为 emby 上游地址
+func Fetch(ctx context.Context, origin, uri, method string, header http.Header, body map[string]any) (model.HttpRes[*jsons.Item], http.Header) {
+	return RawFetch(ctx, origin, uri, method, header, https.MapBody(body))
 }
 
 // RawFetch 请求 emby api 接口, 使用流式请求体
-func RawFetch(uri, method string, header http.Header, body io.ReadCloser) (model.HttpRes[*jsons.Item], http.Header) {
-	u := config.C.Emby.Host + uri
+//
+// origin 为 emby 上游地址, ctx 被取消后会立即中断请求
+func RawFetch(ctx context.Context, origin, uri, method string, header http.Header, body io.ReadCloser) (model.HttpRes[*jsons.Item], http.Header) {
+	u := origin + uri
 
 	// 构造请求头, 发出请求
 	if header == nil {
@@ -45,7 +49,7 @@ func RawFetch(uri, method string, header http.Header, body io.ReadCloser) (model
 		header.Set("Content-Type", "application/json;charset=utf-8")
 	}
 
-	resp, err := https.Request(method, u).Header(header).Body(body).Do()
+	resp, err := https.Request(method, u).Context(ctx).Header(header).Body(body).Do()
 	if err != nil {
 		return model.HttpRes[*jsons.Item]{Code: http.StatusBadRequest, Msg: "请求发送失败: " + err.Error()}, nil
 	}
@@ -7,7 +7,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
@@ -26,8 +25,45 @@ const AuthUri = "/emby/Auth/Keys"
 //
 // 这个 map 不会进行大小限制, 考虑到 emby 原服务器中合法的 api_key 个数不是无限个
 // 所以这里也不用限制太多
+//
+// key 为 "上游名称_api_key", value 为 TrustedApiKey
 var validApiKeys = sync.Map{}
 
+// TrustedApiKey 已经校验通过的 api_key 信息
+type TrustedApiKey struct {
+	Upstream string `json:"upstream"` // 所属的 emby 上游名称
+	ApiKey   string `json:"api_key"`  // api_key
+}
+
+// ListApiKeys 获取所有已经校验通过的 api_key
+func ListApiKeys() []TrustedApiKey {
+	res := make([]TrustedApiKey, 0)
+	validApiKeys.Range(func(key, value any) bool {
+		res = append(res, value.(TrustedApiKey))
+		return true
+	})
+	return res
+}
+
+// RevokeApiKey 吊销已经校验通过的 api_key, 返回吊销的个数
+//
+// 被吊销的 api_key 在下次请求时会重新向 emby 校验,
+// upstream 为空时, 吊销所有上游中的该 api_key
+func RevokeApiKey(upstream, apiKey string) int {
+	cnt := 0
+	validApiKeys.Range(func(key, value any) bool {
+		tk := value.(TrustedApiKey)
+		if tk.ApiKey != apiKey || (upstream != "" && tk.Upstream != upstream) {
+			return true
+		}
+		if _, ok := validApiKeys.LoadAndDelete(key); ok {
+			cnt++
+		}
+		return true
+	})
+	return cnt
+}
+
 // ApiKeyType 标记 emby 支持的不同种 api_key 传递方式
 type ApiKeyType string
 
@@ -68,7 +104,11 @@ func ApiKeyChecker() gin.HandlerFunc {
 		kType, kName, apiKey := getApiKey(c)
 
 		// 2 如果该 key 已经是被信任的, 跳过校验
-		if _, ok := validApiKeys.Load(apiKey); ok {
+		//
+		// 不同上游的 api_key 互不通用, 需要带上上游名称
+		upstream := Upstream(c)
+		trustKey := upstream.Name + "_" + apiKey
+		if _, ok := validApiKeys.Load(trustKey); ok {
 			return
 		}
 
@@ -85,7 +125,7 @@ func ApiKeyChecker() gin.HandlerFunc {
 		}
 
 		// 4 发出请求, 验证 api_key
-		u := config.C.Emby.Host + AuthUri
+		u := upstream.Host + AuthUri
 		var header http.Header
 		if kType == Query {
 			u = urls.AppendArgs(u, kName, apiKey)
@@ -93,16 +133,16 @@ func ApiKeyChecker() gin.HandlerFunc {
 			header = make(http.Header)
 			header.Set(kName, apiKey)
 		}
-		resp, err := https.Get(u).Header(header).Do()
+		resp, err := https.Get(u).Context(c.Request.Context()).Header(header).Do()
 		if err != nil {
-			logs.Error("鉴权失败: %v", err)
+			logs.Ctx(c.Request.Context()).Error("鉴权失败: %v", err)
 			c.Abort()
 			return
 		}
 		defer resp.Body.Close()
 		bodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			logs.Error("鉴权中间件读取源服务器响应失败: %v", err)
+			logs.Ctx(c.Request.Context()).Error("鉴权中间件读取源服务器响应失败: %v", err)
 			bodyBytes = []byte(UnauthorizedResp)
 		}
 		respBody := strings.TrimSpace(string(bodyBytes))
@@ -115,7 +155,7 @@ func ApiKeyChecker() gin.HandlerFunc {
 		}
 
 		// 6 校验通过, 加入信任集合
-		validApiKeys.Store(apiKey, struct{}{})
+		validApiKeys.Store(trustKey, TrustedApiKey{Upstream: upstream.Name, ApiKey: apiKey})
 	}
 }
 
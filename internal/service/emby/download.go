@@ -23,7 +23,7 @@ func HandleSyncDownload(c *gin.Context) {
 	if checkErr(c, err) {
 		return
 	}
-	logs.Info("解析出来的 itemInfo 信息: %v", itemInfo)
+	logs.Ctx(c.Request.Context()).Info("解析出来的 itemInfo 信息: %v", itemInfo)
 	if itemInfo.Id == "" {
 		checkErr(c, errors.New("JobItems id 为空"))
 		return
@@ -31,7 +31,7 @@ func HandleSyncDownload(c *gin.Context) {
 
 	// 请求 targets 列表
 	targetUri := "/Sync/Targets?api_key=" + itemInfo.ApiKey
-	resp, _ := Fetch(targetUri, http.MethodGet, nil, nil)
+	resp, _ := Fetch(c.Request.Context(), itemInfo.Upstream.Host, targetUri, http.MethodGet, nil, nil)
 	if resp.Code != http.StatusOK {
 		checkErr(c, fmt.Errorf("请求 emby 失败: %v, uri: %s", resp.Msg, targetUri))
 		return
@@ -52,7 +52,7 @@ func HandleSyncDownload(c *gin.Context) {
 
 		// 请求 Ready 接口
 		readyUri := readyUriTmpl + id
-		resp, _ := Fetch(readyUri, http.MethodGet, nil, nil)
+		resp, _ := Fetch(c.Request.Context(), itemInfo.Upstream.Host, readyUri, http.MethodGet, nil, nil)
 		if resp.Code != http.StatusOK {
 			checkErr(c, fmt.Errorf("请求 emby 失败: %v, uri: %s", resp.Msg, readyUri))
 			return jsons.ErrBreakRange
@@ -86,7 +86,7 @@ func HandleSyncDownload(c *gin.Context) {
 				breakRange = true
 				return jsons.ErrBreakRange
 			}
-			logs.Success("成功匹配到 itemId: %s, mediaSourceId: %s", itemId, msId)
+			logs.Ctx(c.Request.Context()).Success("成功匹配到 itemId: %s, mediaSourceId: %s", itemId, msId)
 
 			newUrl, _ := url.Parse(fmt.Sprintf("/videos/%s/stream?MediaSourceId=%s&api_key=%s&Static=true", itemId, msId, itemInfo.ApiKey))
 			c.Redirect(http.StatusTemporaryRedirect, newUrl.String())
@@ -124,7 +124,8 @@ func DownloadStrategyChecker() gin.HandlerFunc {
 			return
 		}
 
-		strategy := config.C.Emby.DownloadStrategy
+		upstream := Upstream(c)
+		strategy := upstream.DownloadStrategy
 
 		if strategy == config.DlStrategyDirect {
 			return
@@ -137,8 +138,8 @@ func DownloadStrategyChecker() gin.HandlerFunc {
 		}
 
 		if strategy == config.DlStrategyOrigin {
-			if err := https.ProxyPass(c.Request, c.Writer, config.C.Emby.Host); err != nil {
-				logs.Error("下载接口代理失败: %v", err)
+			if err := https.ProxyPass(c.Request, c.Writer, upstream.Host); err != nil {
+				logs.Ctx(c.Request.Context()).Error("下载接口代理失败: %v", err)
 			}
 		}
 
@@ -2,6 +2,7 @@ package emby
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
@@ -10,7 +11,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/bytess"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
@@ -19,28 +19,40 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ProxySocket 代理 websocket 请求
+//
+// 每个 emby 上游地址复用同一个反向代理实例
 func ProxySocket() func(*gin.Context) {
 
-	var proxy *httputil.ReverseProxy
-	var once = sync.Once{}
+	// proxies 上游地址 => 反向代理实例
+	var proxies sync.Map
+
+	getProxy := func(origin string) (*httputil.ReverseProxy, error) {
+		if p, ok := proxies.Load(origin); ok {
+			return p.(*httputil.ReverseProxy), nil
+		}
 
-	initFunc := func() {
-		origin := config.C.Emby.Host
 		u, err := url.Parse(origin)
 		if err != nil {
-			panic("转换 emby host 异常: " + err.Error())
+			return nil, fmt.Errorf("转换 emby host 异常: %v", err)
 		}
 
-		proxy = httputil.NewSingleHostReverseProxy(u)
-
+		proxy := httputil.NewSingleHostReverseProxy(u)
 		proxy.Director = func(r *http.Request) {
 			r.URL.Scheme = u.Scheme
 			r.URL.Host = u.Host
 		}
+		p, _ := proxies.LoadOrStore(origin, proxy)
+		return p.(*httputil.ReverseProxy), nil
 	}
 
 	return func(c *gin.Context) {
-		once.Do(initFunc)
+		proxy, err := getProxy(Upstream(c).Host)
+		if err != nil {
+			logs.Ctx(c.Request.Context()).Error("代理 websocket 失败: %v", err)
+			c.Status(http.StatusBadGateway)
+			return
+		}
 		proxy.ServeHTTP(c.Writer, c.Request)
 	}
 }
@@ -52,7 +64,7 @@ func HandleImages(c *gin.Context) {
 	q := c.Request.URL.Query()
 	q.Del("quality")
 	q.Del("Quality")
-	q.Set("Quality", strconv.Itoa(config.C.Emby.ImagesQuality))
+	q.Set("Quality", strconv.Itoa(Upstream(c).ImagesQuality))
 	c.Request.RequestURI = c.Request.URL.Path + "?" + q.Encode()
 	ProxyOrigin(c)
 }
@@ -62,14 +74,14 @@ func ProxyOrigin(c *gin.Context) {
 	if c == nil {
 		return
 	}
-	origin := config.C.Emby.Host
+	origin := Upstream(c).Host
 
 	// 传递客户端 IP 到 emby
 	c.Request.Header.Set("X-Forwarded-For", c.ClientIP())
 	c.Request.Header.Set("X-Real-IP", c.ClientIP())
 
 	if err := https.ProxyPass(c.Request, c.Writer, origin); err != nil {
-		logs.Error("代理异常: %v", err)
+		logs.Ctx(c.Request.Context()).Error("代理异常: %v", err)
 	}
 }
 
@@ -112,18 +124,19 @@ func TestProxyUri(c *gin.Context) bool {
 
 	bodyBytes, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		logs.Error("测试 uri 执行异常: %v", err)
+		logs.Ctx(c.Request.Context()).Error("测试 uri 执行异常: %v", err)
 		return false
 	}
 	infos.Body = string(bodyBytes)
 
-	origin := config.C.Emby.Host
+	origin := Upstream(c).Host
 	resp, err := https.Request(infos.Method, origin+infos.Uri).
+		Context(c.Request.Context()).
 		Header(c.Request.Header).
 		Body(io.NopCloser(bytes.NewBuffer(bodyBytes))).
 		Do()
 	if err != nil {
-		logs.Error("测试 uri 执行异常: %v", err)
+		logs.Ctx(c.Request.Context()).Error("测试 uri 执行异常: %v", err)
 		return false
 	}
 	defer resp.Body.Close()
@@ -137,12 +150,12 @@ func TestProxyUri(c *gin.Context) bool {
 
 	bodyBytes, err = io.ReadAll(resp.Body)
 	if err != nil {
-		logs.Error("测试 uri 执行异常: %v", err)
+		logs.Ctx(c.Request.Context()).Error("测试 uri 执行异常: %v", err)
 		return false
 	}
 	infos.RespBody = string(bodyBytes)
 	infos.RespStatus = resp.StatusCode
-	logs.Warn("测试 uri 代理信息: %s", jsons.FromValue(infos))
+	logs.Ctx(c.Request.Context()).Warn("测试 uri 代理信息: %s", jsons.FromValue(infos))
 
 	c.Status(infos.RespStatus)
 	c.Writer.Write(bodyBytes)
@@ -152,7 +165,8 @@ func TestProxyUri(c *gin.Context) bool {
 
 // ProxyRoot web 首页代理
 func ProxyRoot(c *gin.Context) {
-	resp, err := https.Request(c.Request.Method, config.C.Emby.Host+c.Request.URL.String()).
+	resp, err := https.Request(c.Request.Method, Upstream(c).Host+c.Request.URL.String()).
+		Context(c.Request.Context()).
 		Header(c.Request.Header).
 		Body(c.Request.Body).
 		DoSingle()
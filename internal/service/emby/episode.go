@@ -8,7 +8,6 @@ import (
 	"slices"
 	"strconv"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 
 	"github.com/gin-gonic/gin"
@@ -20,8 +19,8 @@ import (
 // 则会将未播剧集排在前面位置
 func ResortEpisodes(c *gin.Context) {
 	// 1 检查配置是否开启
-	if !config.C.Emby.EpisodesUnplayPrior {
-		checkErr(c, https.ProxyPass(c.Request, c.Writer, config.C.Emby.Host))
+	if !Upstream(c).EpisodesUnplayPrior {
+		checkErr(c, https.ProxyPass(c.Request, c.Writer, Upstream(c).Host))
 		return
 	}
 
@@ -37,7 +36,7 @@ func ResortEpisodes(c *gin.Context) {
 
 	// 3 代理请求
 	c.Request.Header.Del("Accept-Encoding")
-	resp, err := https.ProxyRequest(c.Request, config.C.Emby.Host)
+	resp, err := https.ProxyRequest(c.Request, Upstream(c).Host)
 	if checkErr(c, err) {
 		return
 	}
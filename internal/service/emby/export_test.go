@@ -0,0 +1,4 @@
+package emby
+
+// DirectLinkExpired 导出给测试使用
+var DirectLinkExpired = directLinkExpired
@@ -34,7 +34,7 @@ const (
 // ResortRandomItems 对随机的 items 列表进行重排序
 func ResortRandomItems(c *gin.Context) {
 	// 如果没有开启配置, 代理原请求并返回
-	if !config.C.Emby.ResortRandomItems {
+	if !Upstream(c).ResortRandomItems {
 		ProxyOrigin(c)
 		return
 	}
@@ -67,7 +67,7 @@ func ResortRandomItems(c *gin.Context) {
 	defer func() {
 		respBody, _ := json.Marshal(ih)
 		if err != nil {
-			logs.Error("随机排序接口非预期响应, err: %v, 返回原始响应", err)
+			logs.Ctx(c.Request.Context()).Error("随机排序接口非预期响应, err: %v, 返回原始响应", err)
 			respBody = bodyBytes
 		}
 
@@ -99,9 +99,10 @@ func RandomItemsWithLimit(c *gin.Context) {
 	q.Set("Limit", "500")
 	q.Del("SortOrder")
 	u.RawQuery = q.Encode()
-	embyHost := config.C.Emby.Host
+	embyHost := Upstream(c).Host
 	c.Request.Header.Del("Accept-Encoding")
 	resp, err := https.Request(c.Request.Method, embyHost+u.String()).
+		Context(c.Request.Context()).
 		Header(c.Request.Header).
 		Body(c.Request.Body).
 		Do()
@@ -117,6 +118,7 @@ func RandomItemsWithLimit(c *gin.Context) {
 	c.Status(resp.StatusCode)
 	https.CloneHeader(c.Writer, resp.Header)
 	c.Header(cache.HeaderKeyExpired, cache.Duration(time.Hour*3))
+	c.Header(cache.HeaderKeyStale, cache.StaleDuration(time.Hour))
 	c.Header(cache.HeaderKeySpace, ItemsCacheSpace)
 	c.Header(cache.HeaderKeySpaceKey, calcRandomItemsCacheKey(c))
 
@@ -129,7 +131,8 @@ func RandomItemsWithLimit(c *gin.Context) {
 
 // calcRandomItemsCacheKey 计算 random items 在缓存空间中的 key 值
 func calcRandomItemsCacheKey(c *gin.Context) string {
-	return c.Query("IncludeItemTypes") +
+	return Upstream(c).Name + "_" +
+		c.Query("IncludeItemTypes") +
 		c.Query("Recursive") +
 		c.Query("Fields") +
 		c.Query("EnableImageTypes") +
@@ -144,7 +147,7 @@ func calcRandomItemsCacheKey(c *gin.Context) string {
 func ProxyAddItemsPreviewInfo(c *gin.Context) {
 	// 代理请求
 	c.Request.Header.Del("Accept-Encoding")
-	resp, err := https.ProxyRequest(c.Request, config.C.Emby.Host)
+	resp, err := https.ProxyRequest(c.Request, Upstream(c).Host)
 	if checkErr(c, err) {
 		return
 	}
@@ -193,7 +196,7 @@ func ProxyAddItemsPreviewInfo(c *gin.Context) {
 			}
 
 			// 检查用户是否启用了转码版本获取
-			if !config.C.VideoPreview.Enable {
+			if !config.C().VideoPreview.Enable {
 				return nil
 			}
 
@@ -234,7 +237,7 @@ func ProxyAddItemsPreviewInfo(c *gin.Context) {
 func ProxyLatestItems(c *gin.Context) {
 	// 代理请求
 	c.Request.Header.Del("Accept-Encoding")
-	resp, err := https.ProxyRequest(c.Request, config.C.Emby.Host)
+	resp, err := https.ProxyRequest(c.Request, Upstream(c).Host)
 	if checkErr(c, err) {
 		return
 	}
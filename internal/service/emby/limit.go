@@ -0,0 +1,52 @@
+package emby
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/ratelimit"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// ipLimiters 客户端 ip => 限流器
+	ipLimiters ratelimit.Group
+
+	// userLimiters "上游名称_api_key" => 限流器
+	userLimiters ratelimit.Group
+)
+
+// limitClient 请求 openlist 直链前, 按照客户端 ip 以及用户进行限流
+//
+// 返回 true 表示请求已经被限流, 并且已经响应 429
+func limitClient(c *gin.Context, itemInfo ItemInfo) bool {
+	rl := config.C().RateLimit
+	if !rl.Enable {
+		return false
+	}
+
+	ipLimiter := ipLimiters.Get(c.ClientIP(), rl.PerIp.PerMinute, rl.PerIp.Burst)
+	var userLimiter *ratelimit.Limiter
+	if itemInfo.ApiKey != "" {
+		userKey := itemInfo.Upstream.Name + "_" + itemInfo.ApiKey
+		userLimiter = userLimiters.Get(userKey, rl.PerUser.PerMinute, rl.PerUser.Burst)
+	}
+
+	if err := openlist.WaitLimit(c.Request.Context(), openlist.LimitScopeClient, ipLimiter, userLimiter); err != nil {
+		respondLimited(c)
+		return true
+	}
+	return false
+}
+
+// respondLimited 响应 429, 提示客户端稍后重试
+func respondLimited(c *gin.Context) {
+	redirectOutcomes.Inc(RedirectLimited)
+	c.Header(cache.HeaderKeyExpired, "-1")
+	c.Header("Retry-After", strconv.Itoa(config.C().RateLimit.MaxWait))
+	c.String(http.StatusTooManyRequests, "请求过于频繁, 请稍后再试")
+}
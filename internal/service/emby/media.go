@@ -2,6 +2,7 @@ package emby
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"sync"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/path"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
@@ -33,7 +35,7 @@ const MediaSourceIdSegment = "[[_]]"
 //
 // uri 中必须有 query 参数 MediaSourceId,
 // 如果没有携带该参数, 可能会请求到多个媒体, 默认返回第一个媒体的本地路径
-func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
+func getEmbyFileLocalPath(ctx context.Context, itemInfo ItemInfo) (string, error) {
 	var header http.Header
 	switch itemInfo.ApiKeyType {
 	case Header:
@@ -45,7 +47,7 @@ func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
 	}
 
 	innerRequest := func(method string) (*http.Response, error) {
-		resp, err := https.Request(method, config.C.Emby.Host+itemInfo.PlaybackInfoUri).Header(header).Do()
+		resp, err := https.Request(method, itemInfo.Upstream.Host+itemInfo.PlaybackInfoUri).Context(ctx).Header(header).Do()
 		if err != nil {
 			return nil, fmt.Errorf("请求 Emby 接口异常, error: %v", err)
 		}
@@ -123,7 +125,7 @@ func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
 // findVideoPreviewInfos 查找 source 的所有转码资源
 //
 // 传递 resChan 进行异步查询, 通过监听 resChan 获取查询结果
-func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan []*jsons.Item) {
+func findVideoPreviewInfos(ctx context.Context, source *jsons.Item, upstream *config.Emby, clientApiKey string, resChan chan []*jsons.Item) {
 	if resChan == nil {
 		return
 	}
@@ -135,7 +137,7 @@ func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan
 	}
 
 	// 未启用配置
-	cfg := config.C.VideoPreview
+	cfg := config.C().VideoPreview
 	srcContainer, _ := source.Attr("Container").String()
 	if !cfg.Enable || !cfg.ContainerValid(srcContainer) {
 		resChan <- nil
@@ -143,12 +145,12 @@ func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan
 	}
 
 	// 转换 openlist 绝对路径
-	openlistPathRes := path.Emby2Openlist(source.Attr("Path").Val().(string))
+	openlistPathRes := path.Emby2Openlist(ctx, upstream, source.Attr("Path").Val().(string))
 	var transcodingList []openlist.TranscodingVideoInfo
 	var subtitleList []openlist.TranscodingSubtitleInfo
 	firstFetchSuccess := false
 	if openlistPathRes.Success {
-		res := openlist.FetchFsOther(openlistPathRes.Path, nil)
+		res := openlist.FetchFsOther(ctx, openlistPathRes.Path, nil)
 
 		if res.Code == http.StatusOK {
 			firstFetchSuccess = true
@@ -164,15 +166,15 @@ func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan
 
 	// 首次请求失败, 遍历 openlist 所有根目录, 重新请求
 	if !firstFetchSuccess {
-		paths, err := openlistPathRes.Range()
+		paths, err := openlistPathRes.Range(ctx)
 		if err != nil {
-			logs.Error("转换 openlist 路径异常: %v", err)
+			logs.Ctx(ctx).Error("转换 openlist 路径异常: %v", err)
 			resChan <- nil
 			return
 		}
 
 		for _, path := range paths {
-			res := openlist.FetchFsOther(path, nil)
+			res := openlist.FetchFsOther(ctx, path, nil)
 			if res.Code == http.StatusOK {
 				transcodingList = res.Data.VideoPreviewPlayInfo.LiveTranscodingTaskList
 				subtitleList = res.Data.VideoPreviewPlayInfo.LiveTranscodingSubtitleTaskList
@@ -195,7 +197,7 @@ func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if config.C.VideoPreview.IsTemplateIgnore(transcode.TemplateId) {
+			if config.C().VideoPreview.IsTemplateIgnore(transcode.TemplateId) {
 				// 当前清晰度被忽略
 				return
 			}
@@ -379,7 +381,7 @@ func resolveItemInfo(c *gin.Context, routeType RouteType) (ItemInfo, error) {
 
 	// 匹配 item id
 	uri := c.Request.URL.Path
-	itemInfo := ItemInfo{RouteType: routeType}
+	itemInfo := ItemInfo{RouteType: routeType, Upstream: Upstream(c)}
 	switch routeType {
 	case RouteItems:
 		itemInfo.Id = filepath.Base(uri)
@@ -388,6 +390,7 @@ func resolveItemInfo(c *gin.Context, routeType RouteType) (ItemInfo, error) {
 	default:
 		return ItemInfo{}, fmt.Errorf("不支持的 RouteType: %s", routeType)
 	}
+	c.Set(constant.ItemIdGinKey, itemInfo.Id)
 
 	// 获取客户端请求的 api_key
 	itemInfo.ApiKeyType, itemInfo.ApiKeyName, itemInfo.ApiKey = getApiKey(c)
@@ -492,7 +495,7 @@ func getAllPreviewTemplateIds() []string {
 
 	res := make([]string, 0, len(allIds))
 	for _, id := range allIds {
-		if config.C.VideoPreview.IsTemplateIgnore(id) {
+		if config.C().VideoPreview.IsTemplateIgnore(id) {
 			continue
 		}
 		res = append(res, id)
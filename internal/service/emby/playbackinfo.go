@@ -2,6 +2,7 @@ package emby
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
@@ -47,7 +49,7 @@ var (
 func TransferPlaybackInfo(c *gin.Context) {
 	// 1 解析资源信息
 	itemInfo, err := resolveItemInfo(c, RoutePlaybackInfo)
-	logs.Info("ItemInfo 解析结果: %s", itemInfo)
+	logs.Ctx(c.Request.Context()).Info("ItemInfo 解析结果: %s", itemInfo)
 	if checkErr(c, err) {
 		return
 	}
@@ -69,7 +71,7 @@ func TransferPlaybackInfo(c *gin.Context) {
 	c.Request.Header.Del("Accept-Encoding")
 	originRequestBody := c.Request.Body
 	c.Request.Body = io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
-	res, respHeader := RawFetch(itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
+	res, respHeader := RawFetch(c.Request.Context(), itemInfo.Upstream.Host, itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		checkErr(c, errors.New(res.Msg))
 		return
@@ -111,7 +113,7 @@ func TransferPlaybackInfo(c *gin.Context) {
 
 		// 如果是本地媒体, 不处理
 		embyPath, _ := source.Attr("Path").String()
-		if strings.HasPrefix(embyPath, config.C.Emby.LocalMediaRoot) {
+		if strings.HasPrefix(embyPath, itemInfo.Upstream.LocalMediaRoot) {
 			return nil
 		}
 
@@ -141,12 +143,12 @@ func TransferPlaybackInfo(c *gin.Context) {
 		}
 
 		// 添加转码 MediaSource 获取
-		cfg := config.C.VideoPreview
+		cfg := config.C().VideoPreview
 		if !msInfo.Empty || !cfg.Enable || !cfg.ContainerValid(source.Attr("Container").Val().(string)) {
 			return nil
 		}
 		resChan := make(chan []*jsons.Item, 1)
-		go findVideoPreviewInfos(source, itemInfo.ApiKey, resChan)
+		go findVideoPreviewInfos(c.Request.Context(), source, itemInfo.Upstream, itemInfo.ApiKey, resChan)
 		resChans = append(resChans, resChan)
 		return nil
 	})
@@ -156,8 +158,9 @@ func TransferPlaybackInfo(c *gin.Context) {
 	}
 
 	defer func() {
-		// 缓存 12h
+		// 缓存 12h, 过期后 12h 内仍使用旧的缓存, 并在后台刷新
 		c.Header(cache.HeaderKeyExpired, cache.Duration(time.Hour*12))
+		c.Header(cache.HeaderKeyStale, cache.StaleDuration(time.Hour*12))
 		// 将请求结果缓存到指定缓存空间下
 		c.Header(cache.HeaderKeySpace, PlaybackCacheSpace)
 		c.Header(cache.HeaderKeySpaceKey, calcPlaybackInfoSpaceCacheKey(itemInfo))
@@ -185,7 +188,7 @@ func handleSpecialPlayback(c *gin.Context, itemInfo ItemInfo) bool {
 	c.Request.Header.Del("Accept-Encoding")
 	originRequestBody := c.Request.Body
 	c.Request.Body = io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
-	res, _ := RawFetch(itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
+	res, _ := RawFetch(c.Request.Context(), itemInfo.Upstream.Host, itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		return false
 	}
@@ -203,8 +206,8 @@ func handleSpecialPlayback(c *gin.Context, itemInfo ItemInfo) bool {
 
 		// 本地媒体
 		path, _ := value.Attr("Path").String()
-		if strings.HasPrefix(path, config.C.Emby.LocalMediaRoot) {
-			logs.Info("本地媒体: %s, 回源处理", path)
+		if strings.HasPrefix(path, itemInfo.Upstream.LocalMediaRoot) {
+			logs.Ctx(c.Request.Context()).Info("本地媒体: %s, 回源处理", path)
 			flag = true
 		}
 
@@ -237,7 +240,7 @@ func useCacheSpacePlaybackInfo(c *gin.Context, itemInfo ItemInfo) bool {
 	}
 	reqId := itemInfo.MsInfo.RawId
 
-	if !config.C.Cache.Enable {
+	if !config.C().Cache.Enable {
 		// 未开启缓存功能
 		return false
 	}
@@ -307,7 +310,7 @@ func useCacheSpacePlaybackInfo(c *gin.Context, itemInfo ItemInfo) bool {
 	findMediaSourceAndReturn := func(spaceCache cache.RespCache) bool {
 		jsonBody, err := spaceCache.JsonBody()
 		if err != nil {
-			logs.Error("解析缓存响应体失败: %v", err)
+			logs.Ctx(c.Request.Context()).Error("解析缓存响应体失败: %v", err)
 			return false
 		}
 
@@ -361,8 +364,8 @@ func useCacheSpacePlaybackInfo(c *gin.Context, itemInfo ItemInfo) bool {
 	}
 
 	// 如果是单个查询, 则手动请求一次全量
-	if _, err := fetchFullPlaybackInfo(itemInfo); err != nil {
-		logs.Error("更新缓存空间 PlaybackInfo 信息异常: %v", err)
+	if _, err := fetchFullPlaybackInfo(c.Request.Context(), itemInfo); err != nil {
+		logs.Ctx(c.Request.Context()).Error("更新缓存空间 PlaybackInfo 信息异常: %v", err)
 		c.String(http.StatusInternalServerError, "查无缓存, 请稍后尝试重新播放")
 		return true
 	}
@@ -394,7 +397,7 @@ func LoadCacheItems(c *gin.Context) {
 	}()
 
 	// 未开启转码资源获取功能
-	if !config.C.VideoPreview.Enable {
+	if !config.C().VideoPreview.Enable {
 		return
 	}
 
@@ -414,7 +417,7 @@ func LoadCacheItems(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	logs.Info("itemInfo 解析结果: %s", itemInfo)
+	logs.Ctx(c.Request.Context()).Info("itemInfo 解析结果: %s", itemInfo)
 
 	// coverMediaSources 解析 PlaybackInfo 中的 MediaSources 属性
 	// 并覆盖到当前请求的响应中
@@ -439,16 +442,16 @@ func LoadCacheItems(c *gin.Context) {
 	}
 
 	// 缓存空间中没有当前 Item 的 PlaybackInfo 数据, 手动请求
-	bodyJson, err := fetchFullPlaybackInfo(itemInfo)
+	bodyJson, err := fetchFullPlaybackInfo(c.Request.Context(), itemInfo)
 	if err != nil {
-		logs.Warn("更新 Items 缓存异常: %v", err)
+		logs.Ctx(c.Request.Context()).Warn("更新 Items 缓存异常: %v", err)
 		return
 	}
 	coverMediaSources(bodyJson)
 }
 
 // fetchFullPlaybackInfo 请求全量的 PlaybackInfo 信息
-func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
+func fetchFullPlaybackInfo(ctx context.Context, itemInfo ItemInfo) (*jsons.Item, error) {
 	u, err := url.Parse(config.ServerInternalRequestHost() + itemInfo.PlaybackInfoUri)
 	if err != nil {
 		return nil, fmt.Errorf("PlaybackInfo 地址异常: %v, uri: %s", err, itemInfo.PlaybackInfoUri)
@@ -463,7 +466,11 @@ func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
 	if itemInfo.ApiKeyType == Header {
 		header.Set(itemInfo.ApiKeyName, itemInfo.ApiKey)
 	}
-	resp, err := https.Post(u.String()).Header(header).Body(reqBody).Do()
+	// 自请求需要保持与原始请求一致的上游
+	header.Set(constant.HeaderEmbyUpstream, itemInfo.Upstream.Name)
+	header.Set(constant.HeaderInternalToken, config.InternalToken())
+	header.Set(constant.HeaderRequestId, logs.RequestId(ctx))
+	resp, err := https.Post(u.String()).Context(ctx).Header(header).Body(reqBody).Do()
 	if err != nil {
 		return nil, fmt.Errorf("获取全量 PlaybackInfo 失败: %v", err)
 	}
@@ -481,7 +488,7 @@ func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
 
 // calcPlaybackInfoSpaceCacheKey 根据请求的 item 信息计算 PlaybackInfo 在缓存空间中的 key
 func calcPlaybackInfoSpaceCacheKey(itemInfo ItemInfo) string {
-	return itemInfo.Id + "_" + itemInfo.ApiKey
+	return itemInfo.Upstream.Name + "_" + itemInfo.Id + "_" + itemInfo.ApiKey
 }
 
 // getPlaybackInfoByCacheSpace 从缓存空间中获取 PlaybackInfo 信息
@@ -0,0 +1,52 @@
+package emby
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+)
+
+// PurgeItemCache 清理指定 item 相关的缓存, 返回清理的条目数
+//
+// 包括 PlaybackInfo 缓存空间, 包含该 item 的随机列表缓存, 以及该 item 的资源直链, 字幕等请求缓存
+//
+// upstream 为空时, 清理所有 emby 上游的缓存
+func PurgeItemCache(upstream, itemId string) int {
+	if itemId == "" {
+		return 0
+	}
+
+	// PlaybackInfo 缓存空间的 key 格式: 上游名称_itemId_apiKey
+	cnt := cache.PurgeSpaceFunc(PlaybackCacheSpace, func(spaceKey string, _ cache.RespCache) bool {
+		if upstream != "" {
+			return strings.HasPrefix(spaceKey, upstream+"_"+itemId+"_")
+		}
+		return strings.Contains(spaceKey, "_"+itemId+"_")
+	})
+
+	cnt += PurgeItemLists(upstream, itemId)
+
+	// 资源直链, 字幕等请求缓存
+	itemReg := regexp.MustCompile(`(?i)/(videos|audio|items|sync/items)/` + regexp.QuoteMeta(itemId) + `(/|\?|$)`)
+	cnt += cache.PurgeRegex(itemReg)
+	return cnt
+}
+
+// PurgeItemLists 清理随机列表缓存
+//
+// itemId 不为空时, 只清理响应体中包含该 item 的列表, 用于条目的用户数据 (播放进度, 收藏等) 变更;
+// itemId 为空时, 清理所有列表, 用于媒体库新增条目
+//
+// upstream 为空时, 清理所有 emby 上游的缓存
+func PurgeItemLists(upstream, itemId string) int {
+	// 随机列表的 key 不包含 itemId, 需要判断响应体中是否包含该 item
+	idField := []byte(`"Id":"` + itemId + `"`)
+	return cache.PurgeSpaceFunc(ItemsCacheSpace, func(spaceKey string, rc cache.RespCache) bool {
+		if upstream != "" && !strings.HasPrefix(spaceKey, upstream+"_") {
+			return false
+		}
+		return itemId == "" || bytes.Contains(rc.BodyBytes(), idField)
+	})
+}
@@ -1,6 +1,7 @@
 package emby
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -13,6 +14,7 @@ import (
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/path"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
@@ -20,6 +22,28 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// 直链重定向的结果
+const (
+	RedirectDirect    = "direct"    // 重定向到 openlist 直链
+	RedirectTranscode = "transcode" // 重定向到本地 m3u8 转码代理
+	RedirectStrm      = "strm"      // 重定向到 strm 远程地址
+	RedirectLocal     = "local"     // 本地媒体, 回源处理
+	RedirectOrigin    = "origin"    // 获取直链失败, 回源处理
+	RedirectError     = "error"     // 获取直链失败, 直接返回错误
+	RedirectLimited   = "limited"   // 获取直链被限流, 返回 429
+)
+
+const (
+	// DirectLinkDefaultExpired 无法解析直链的过期时间时, 直链的缓存时间
+	DirectLinkDefaultExpired = time.Minute * 10
+
+	// DirectLinkExpiryMargin 直链缓存需要提前于直链过期的时间, 避免客户端拿到即将失效的直链
+	DirectLinkExpiryMargin = time.Minute
+)
+
+// redirectOutcomes 直链重定向结果统计
+var redirectOutcomes = metrics.NewCounterVec("ge2o_redirect_total", "资源直链重定向的结果统计", "outcome")
+
 // Redirect2Transcode 将 master 请求重定向到本地 ts 代理
 func Redirect2Transcode(c *gin.Context) {
 	templateId := c.Query("template_id")
@@ -58,10 +82,10 @@ func Redirect2Transcode(c *gin.Context) {
 func Redirect2OpenlistLink(c *gin.Context) {
 	// 1 解析要请求的资源信息
 	itemInfo, err := resolveItemInfo(c, RouteStream)
-	if checkErr(c, err) {
+	if checkRedirectErr(c, err) {
 		return
 	}
-	logs.Info("解析到的 itemInfo: %v", itemInfo)
+	logs.Ctx(c.Request.Context()).Info("解析到的 itemInfo: %v", itemInfo)
 
 	// 2 如果请求的是转码资源, 重定向到本地的 m3u8 代理服务
 	msInfo := itemInfo.MsInfo
@@ -73,49 +97,62 @@ func Redirect2OpenlistLink(c *gin.Context) {
 		q.Set(QueryApiKeyName, itemInfo.ApiKey)
 		q.Set("openlist_path", itemInfo.MsInfo.OpenlistPath)
 		u.RawQuery = q.Encode()
-		logs.Success("重定向 playlist: %s", u.String())
+		logs.Ctx(c.Request.Context()).Success("重定向 playlist: %s", u.String())
+		redirectOutcomes.Inc(RedirectTranscode)
 		c.Redirect(http.StatusTemporaryRedirect, u.String())
 		return
 	}
 
 	// 3 请求资源在 Emby 中的 Path 参数
-	embyPath, err := getEmbyFileLocalPath(itemInfo)
-	if checkErr(c, err) {
+	ctx := c.Request.Context()
+	embyPath, err := getEmbyFileLocalPath(ctx, itemInfo)
+	if checkRedirectErr(c, err) {
 		return
 	}
 
 	// 4 如果是远程地址 (strm), 重定向处理
 	if urls.IsRemote(embyPath) {
-		finalPath := config.C.Emby.Strm.MapPath(embyPath)
-		finalPath = getFinalRedirectLink(finalPath, c.Request.Header.Clone())
-		logs.Success("重定向 strm: %s", finalPath)
-		c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
+		finalPath := itemInfo.Upstream.Strm.MapPath(embyPath)
+		finalPath = getFinalRedirectLink(ctx, finalPath, c.Request.Header.Clone())
+		logs.Ctx(c.Request.Context()).Success("重定向 strm: %s", finalPath)
+		redirectOutcomes.Inc(RedirectStrm)
+		c.Header(cache.HeaderKeyExpired, directLinkExpired(finalPath))
 		c.Redirect(http.StatusTemporaryRedirect, finalPath)
 		return
 	}
 
 	// 5 如果是本地地址, 回源处理
-	if strings.HasPrefix(embyPath, config.C.Emby.LocalMediaRoot) {
-		logs.Info("本地媒体: %s, 回源处理", embyPath)
+	if strings.HasPrefix(embyPath, itemInfo.Upstream.LocalMediaRoot) {
+		logs.Ctx(c.Request.Context()).Info("本地媒体: %s, 回源处理", embyPath)
+		redirectOutcomes.Inc(RedirectLocal)
 		newUri := strings.Replace(c.Request.RequestURI, "stream", "original", 1)
 		c.Redirect(http.StatusTemporaryRedirect, newUri)
 		return
 	}
 
-	// 6 请求 openlist 资源
+	// 6 请求 openlist 资源, 请求之前先进行限流, 避免触发网盘风控
+	if limitClient(c, itemInfo) {
+		return
+	}
 	fi := openlist.FetchInfo{
 		Header:       c.Request.Header.Clone(),
 		UseTranscode: useTranscode,
 		Format:       msInfo.TemplateId,
 	}
-	openlistPathRes := path.Emby2Openlist(embyPath)
+	openlistPathRes := path.Emby2Openlist(ctx, itemInfo.Upstream, embyPath)
 
 	allErrors := strings.Builder{}
 	// handleOpenlistResource 根据传递的 path 请求 openlist 资源
 	handleOpenlistResource := func(path string) bool {
-		logs.Info("尝试请求 Openlist 资源: %s", path)
+		logs.Ctx(c.Request.Context()).Info("尝试请求 Openlist 资源: %s", path)
 		fi.Path = path
-		res := openlist.FetchResource(fi)
+		res := openlist.FetchResource(ctx, fi)
+
+		// 全局限流, 无需再尝试其他路径
+		if res.Code == http.StatusTooManyRequests {
+			respondLimited(c)
+			return true
+		}
 
 		if res.Code != http.StatusOK {
 			allErrors.WriteString(fmt.Sprintf("请求 Openlist 失败, code: %d, msg: %s, path: %s;", res.Code, res.Msg, path))
@@ -124,9 +161,10 @@ func Redirect2OpenlistLink(c *gin.Context) {
 
 		// 处理直链
 		if !fi.UseTranscode {
-			res.Data.Url = config.C.Emby.Strm.MapPath(res.Data.Url)
-			logs.Success("请求成功, 重定向到: %s", res.Data.Url)
-			c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
+			res.Data.Url = itemInfo.Upstream.Strm.MapPath(res.Data.Url)
+			logs.Ctx(c.Request.Context()).Success("请求成功, 重定向到: %s", res.Data.Url)
+			redirectOutcomes.Inc(RedirectDirect)
+			c.Header(cache.HeaderKeyExpired, directLinkExpired(res.Data.Url))
 			c.Redirect(http.StatusTemporaryRedirect, res.Data.Url)
 			return true
 		}
@@ -138,6 +176,7 @@ func Redirect2OpenlistLink(c *gin.Context) {
 		q.Set(QueryApiKeyName, itemInfo.ApiKey)
 		q.Set("openlist_path", openlist.PathEncode(path))
 		u.RawQuery = q.Encode()
+		redirectOutcomes.Inc(RedirectTranscode)
 		c.Redirect(http.StatusTemporaryRedirect, u.String())
 		return true
 	}
@@ -145,8 +184,8 @@ func Redirect2OpenlistLink(c *gin.Context) {
 	if openlistPathRes.Success && handleOpenlistResource(openlistPathRes.Path) {
 		return
 	}
-	paths, err := openlistPathRes.Range()
-	if checkErr(c, err) {
+	paths, err := openlistPathRes.Range(ctx)
+	if checkRedirectErr(c, err) {
 		return
 	}
 	if slices.ContainsFunc(paths, func(path string) bool {
@@ -155,7 +194,7 @@ func Redirect2OpenlistLink(c *gin.Context) {
 		return
 	}
 
-	checkErr(c, fmt.Errorf("获取直链失败: %s", allErrors.String()))
+	checkRedirectErr(c, fmt.Errorf("获取直链失败: %s", allErrors.String()))
 }
 
 // ProxyOriginalResource 拦截 original 接口
@@ -165,19 +204,49 @@ func ProxyOriginalResource(c *gin.Context) {
 		return
 	}
 
-	embyPath, err := getEmbyFileLocalPath(itemInfo)
+	embyPath, err := getEmbyFileLocalPath(c.Request.Context(), itemInfo)
 	if checkErr(c, err) {
 		return
 	}
 
 	// 如果是本地媒体, 代理回源
-	if strings.HasPrefix(embyPath, config.C.Emby.LocalMediaRoot) {
+	if strings.HasPrefix(embyPath, itemInfo.Upstream.LocalMediaRoot) {
 		ProxyOrigin(c)
 		return
 	}
 	Redirect2OpenlistLink(c)
 }
 
+// checkRedirectErr 检查直链重定向过程中的错误, 并记录重定向结果
+func checkRedirectErr(c *gin.Context, err error) bool {
+	if err == nil || c == nil {
+		return false
+	}
+	if Upstream(c).ProxyErrorStrategy == config.PeStrategyReject {
+		redirectOutcomes.Inc(RedirectError)
+	} else {
+		redirectOutcomes.Inc(RedirectOrigin)
+	}
+	return checkErr(c, err)
+}
+
+// directLinkExpired 根据直链自身的过期时间计算缓存过期时间
+//
+// 缓存会在直链过期前 DirectLinkExpiryMargin 失效, 直链即将过期或已经过期时不缓存;
+// 无法解析直链的过期时间时, 缓存 DirectLinkDefaultExpired
+func directLinkExpired(link string) string {
+	now := time.Now()
+	expiry, ok := urls.LinkExpiry(link, now)
+	if !ok {
+		return cache.Duration(DirectLinkDefaultExpired)
+	}
+	ttl := expiry.Sub(now) - DirectLinkExpiryMargin
+	if ttl <= 0 {
+		return "-1"
+	}
+	return cache.Duration(ttl)
+}
+
 // checkErr 检查 err 是否为空
 // 不为空则根据错误处理策略返回响应
 //
@@ -191,13 +260,13 @@ func checkErr(c *gin.Context, err error) bool {
 	c.Header(cache.HeaderKeyExpired, "-1")
 
 	// 采用拒绝策略, 直接返回错误
-	if config.C.Emby.ProxyErrorStrategy == config.PeStrategyReject {
-		logs.Error("代理接口失败: %v", err)
+	if Upstream(c).ProxyErrorStrategy == config.PeStrategyReject {
+		logs.Ctx(c.Request.Context()).Error("代理接口失败: %v", err)
 		c.String(http.StatusInternalServerError, "代理接口失败, 请检查日志")
 		return true
 	}
 
-	logs.Error("代理接口失败: %v, 回源处理", err)
+	logs.Ctx(c.Request.Context()).Error("代理接口失败: %v, 回源处理", err)
 	ProxyOrigin(c)
 	return true
 }
@@ -205,10 +274,10 @@ func checkErr(c *gin.Context, err error) bool {
 // getFinalRedirectLink 尝试对带有重定向的原始链接进行内部请求, 返回最终链接
 //
 // 请求中途出现任何失败都会返回原始链接
-func getFinalRedirectLink(originLink string, header http.Header) string {
-	finalLink, resp, err := https.Get(originLink).Header(header).DoRedirect()
+func getFinalRedirectLink(ctx context.Context, originLink string, header http.Header) string {
+	finalLink, resp, err := https.Get(originLink).Context(ctx).Header(header).DoRedirect()
 	if err != nil {
-		logs.Warn("内部重定向失败: %v", err)
+		logs.Ctx(ctx).Warn("内部重定向失败: %v", err)
 		return originLink
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,47 @@
+package emby_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+)
+
+func TestDirectLinkExpired(t *testing.T) {
+	now := time.Now()
+	link := func(expiry time.Time) string {
+		return fmt.Sprintf("https://cdn.example.com/v.mp4?Expires=%d&Signature=x", expiry.Unix())
+	}
+
+	// 已经过期或即将过期的直链不缓存
+	for _, l := range []string{
+		link(now.Add(-time.Hour)),
+		link(now.Add(emby.DirectLinkExpiryMargin / 2)),
+	} {
+		if got := emby.DirectLinkExpired(l); got != "-1" {
+			t.Errorf("直链: %s, 期望不缓存, 实际: %s", l, got)
+		}
+	}
+
+	// 缓存在直链过期前失效
+	expiry := now.Add(time.Hour)
+	got, err := strconv.ParseInt(emby.DirectLinkExpired(link(expiry)), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := expiry.Add(-emby.DirectLinkExpiryMargin)
+	if diff := time.UnixMilli(got).Sub(want); diff < -time.Second || diff > time.Second {
+		t.Errorf("缓存过期时间异常, 期望: %v, 实际: %v", want, time.UnixMilli(got))
+	}
+
+	// 无法解析过期时间时使用默认缓存时间
+	got, err = strconv.ParseInt(emby.DirectLinkExpired("https://cdn.example.com/v.mp4?sign=abc"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.UnixMilli(got).Sub(now); d < emby.DirectLinkDefaultExpired-time.Second || d > emby.DirectLinkDefaultExpired+time.Second {
+		t.Errorf("默认缓存时间异常: %v", d)
+	}
+}
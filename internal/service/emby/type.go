@@ -3,6 +3,8 @@ package emby
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 )
 
 // MsInfo MediaSourceId 解析信息
@@ -37,19 +39,24 @@ const (
 
 // ItemInfo emby 资源 item 解析信息
 type ItemInfo struct {
-	Id              string     // item id
-	MsInfo          MsInfo     // MediaSourceId 解析信息
-	ApiKey          string     // emby 接口密钥
-	ApiKeyType      ApiKeyType // emby 接口密钥类型
-	ApiKeyName      string     // emby 接口密钥名称
-	PlaybackInfoUri string     // item 信息查询接口 uri, 通过源服务器查询
+	Id              string       // item id
+	MsInfo          MsInfo       // MediaSourceId 解析信息
+	ApiKey          string       // emby 接口密钥
+	ApiKeyType      ApiKeyType   // emby 接口密钥类型
+	ApiKeyName      string       // emby 接口密钥名称
+	PlaybackInfoUri string       // item 信息查询接口 uri, 通过源服务器查询
+	Upstream        *config.Emby // 请求对应的 emby 上游
 	RouteType
 }
 
 // String 序列化输出
 func (ii ItemInfo) String() string {
-	return fmt.Sprintf("ItemInfo{Id: [%s], MsInfo: [%v], ApiKey: [%s], ApiKeyType: [%s], ApiKeyName: [%s], PlaybackInfoUri: [%s], RouteType: [%s]}",
-		ii.Id, ii.MsInfo, ii.ApiKey, ii.ApiKeyType, ii.ApiKeyName, ii.PlaybackInfoUri, ii.RouteType)
+	upstream := ""
+	if ii.Upstream != nil {
+		upstream = ii.Upstream.Name
+	}
+	return fmt.Sprintf("ItemInfo{Id: [%s], MsInfo: [%v], ApiKey: [%s], ApiKeyType: [%s], ApiKeyName: [%s], PlaybackInfoUri: [%s], Upstream: [%s], RouteType: [%s]}",
+		ii.Id, ii.MsInfo, ii.ApiKey, ii.ApiKeyType, ii.ApiKeyName, ii.PlaybackInfoUri, upstream, ii.RouteType)
 }
 
 // ItemsHolder Emby Items 接口响应接收结构
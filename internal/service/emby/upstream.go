@@ -0,0 +1,43 @@
+package emby
+
+import (
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpstreamResolver 根据监听端口以及请求 Host 匹配 emby 上游, 存放到 Gin 上下文中
+//
+// 服务内部的自请求可以通过请求头直接指定上游名称, 需要同时携带自请求凭证
+func UpstreamResolver() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		upstream := resolveUpstream(c)
+		c.Request.Header.Del(constant.HeaderEmbyUpstream)
+		c.Request.Header.Del(constant.HeaderInternalToken)
+		c.Set(constant.EmbyUpstreamGinKey, upstream)
+	}
+}
+
+// resolveUpstream 解析当前请求对应的 emby 上游
+func resolveUpstream(c *gin.Context) *config.Emby {
+	if name := c.GetHeader(constant.HeaderEmbyUpstream); name != "" && config.IsInternalRequest(c.Request.Header) {
+		if e, ok := config.C().FindEmby(name); ok {
+			return e
+		}
+	}
+	return config.C().MatchEmby(c.GetString(webport.GinKey), c.Request.Host)
+}
+
+// Upstream 获取当前请求对应的 emby 上游
+//
+// 上下文中不存在时, 返回默认上游
+func Upstream(c *gin.Context) *config.Emby {
+	if c != nil {
+		if e, ok := c.Get(constant.EmbyUpstreamGinKey); ok {
+			return e.(*config.Emby)
+		}
+	}
+	return config.C().Emby
+}
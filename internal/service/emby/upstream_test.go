@@ -0,0 +1,69 @@
+package emby_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpstreamResolver(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := `
+emby:
+  host: http://family:8096
+emby-upstreams:
+  - name: friends
+    host: http://friends:8096
+    match-ports: ["8099"]
+openlist:
+  host: http://127.0.0.1:5244
+  token: stub-token
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(emby.UpstreamResolver())
+	r.GET("/", func(c *gin.Context) {
+		// 凭证以及上游请求头不能继续向上游透传
+		if c.GetHeader(constant.HeaderEmbyUpstream) != "" || c.GetHeader(constant.HeaderInternalToken) != "" {
+			t.Error("自请求相关的请求头未被移除")
+		}
+		c.String(http.StatusOK, emby.Upstream(c).Name)
+	})
+
+	cases := []struct {
+		name, token, want string
+	}{
+		{name: "无凭证", want: config.DefaultUpstreamName},
+		{name: "伪造凭证", token: "forged", want: config.DefaultUpstreamName},
+		{name: "合法凭证", token: config.InternalToken(), want: "friends"},
+	}
+	for _, tc := range cases {
+		// 反向代理部署在本机时, 外部请求同样来自回环地址
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "127.0.0.1:50000"
+		req.Header.Set(constant.HeaderEmbyUpstream, "friends")
+		if tc.token != "" {
+			req.Header.Set(constant.HeaderInternalToken, tc.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tc.want {
+			t.Errorf("%s: 期望上游: %s, 实际: %s", tc.name, tc.want, w.Body.String())
+		}
+	}
+}
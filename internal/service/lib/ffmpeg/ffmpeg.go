@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os/exec"
 	"sync"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
 )
 
 // OpenError ffmpeg 打开文件失败
@@ -14,8 +16,21 @@ const OpenError = "Error opening input:"
 // mu 任务逐个执行
 var mu sync.Mutex
 
+// probes ffmpeg 解析次数统计
+var probes = metrics.NewCounterVec("ge2o_ffmpeg_probes_total", "ffmpeg 解析媒体文件的次数", "type", "result")
+
+// recordProbe 记录一次解析结果
+func recordProbe(typ string, err error) {
+	if err != nil {
+		probes.Inc(typ, "error")
+		return
+	}
+	probes.Inc(typ, "success")
+}
+
 // InspectInfo 检查指定路径文件的元信息
-func InspectInfo(path string) (Info, error) {
+func InspectInfo(path string) (i Info, err error) {
+	defer func() { recordProbe("info", err) }()
 	if !execOk {
 		return Info{}, errors.New("ffmpeg 未初始化")
 	}
@@ -29,7 +44,6 @@ func InspectInfo(path string) (Info, error) {
 		return Info{}, errors.New(string(outputBytes[bytes.Index(outputBytes, []byte(OpenError)):]))
 	}
 
-	i := Info{}
 	if durationReg.Match(outputBytes) {
 		i.Duration = resolveDuration(string(outputBytes))
 	}
@@ -38,7 +52,8 @@ func InspectInfo(path string) (Info, error) {
 }
 
 // InspectMusic 检查指定音乐文件的元信息
-func InspectMusic(path string) (Music, error) {
+func InspectMusic(path string) (m Music, err error) {
+	defer func() { recordProbe("music", err) }()
 	if !execOk {
 		return Music{}, errors.New("ffmpeg 未初始化")
 	}
@@ -52,7 +67,6 @@ func InspectMusic(path string) (Music, error) {
 		return Music{}, errors.New(string(outputBytes[bytes.Index(outputBytes, []byte(OpenError)):]))
 	}
 
-	m := Music{}
 	wg := sync.WaitGroup{}
 	output := string(outputBytes)
 	wg.Add(11)
@@ -129,7 +143,8 @@ func InspectMusic(path string) (Music, error) {
 }
 
 // ExtractMusicCover 解析音乐海报
-func ExtractMusicCover(path string) ([]byte, error) {
+func ExtractMusicCover(path string) (cover []byte, err error) {
+	defer func() { recordProbe("cover", err) }()
 	if !execOk {
 		return nil, errors.New("ffmpeg 未初始化")
 	}
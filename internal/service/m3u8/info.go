@@ -3,6 +3,7 @@ package m3u8
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -221,8 +222,8 @@ func (i *Info) UpdateContent() error {
 	}
 	logs.Progress("更新 playlist, openlistPath: %s, templateId: %s", i.OpenlistPath, i.TemplateId)
 
-	// 请求 openlist 资源
-	res := openlist.FetchResource(openlist.FetchInfo{
+	// 请求 openlist 资源, playlist 由多个客户端共享, 不跟随单个请求取消
+	res := openlist.FetchResource(context.Background(), openlist.FetchInfo{
 		Path:         i.OpenlistPath,
 		UseTranscode: true,
 		Format:       i.TemplateId,
@@ -3,9 +3,11 @@ package m3u8
 import (
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
 )
 
@@ -19,7 +21,27 @@ const (
 	PreChanSize = 1000
 )
 
+// playlistNum 内存中正在维护的播放列表个数
+var playlistNum atomic.Int64
+
+var (
+	// stopChan 关闭时通知维护 goroutine 退出
+	stopChan = make(chan struct{})
+
+	// stopped 维护 goroutine 退出时关闭
+	stopped = make(chan struct{})
+
+	stopOnce sync.Once
+)
+
+// Stop 停止播放列表维护 goroutine
+func Stop() {
+	stopOnce.Do(func() { close(stopChan) })
+	<-stopped
+}
+
 func init() {
+	metrics.NewGaugeFunc("ge2o_m3u8_playlists", "内存中正在维护的 m3u8 播放列表个数", func() float64 { return float64(playlistNum.Load()) })
 	go loopMaintainPlaylist()
 }
 
@@ -32,6 +54,12 @@ var GetTsLink func(openlistPath, templateId string, idx int) (string, bool)
 // GetSubtitleLink 获取字幕链接
 var GetSubtitleLink func(openlistPath, templateId, subName string) (string, bool)
 
+// ListPlaylists 获取内存中正在维护的 m3u8 播放列表概要
+var ListPlaylists func() []Summary
+
+// listReqChan 列表查询通道, 由维护 goroutine 生成快照后回写
+var listReqChan = make(chan chan []Summary)
+
 // preMaintainInfoChan 预处理通道
 //
 // 外界将需要维护的信息放到这个通道中, 由 goroutine 单线程维护内存
@@ -175,6 +203,28 @@ func loopMaintainPlaylist() {
 		return "", false
 	}
 
+	ListPlaylists = func() []Summary {
+		resChan := make(chan []Summary, 1)
+		listReqChan <- resChan
+		return <-resChan
+	}
+
+	// snapshot 生成内存中所有 info 的概要信息
+	snapshot := func() []Summary {
+		res := make([]Summary, 0, len(infoArr))
+		for _, info := range infoArr {
+			res = append(res, Summary{
+				OpenlistPath: info.OpenlistPath,
+				TemplateId:   info.TemplateId,
+				TsNum:        len(info.RemoteTsInfos),
+				SubtitleNum:  len(info.Subtitles),
+				LastRead:     time.UnixMilli(info.LastRead),
+				LastUpdate:   time.UnixMilli(info.LastUpdate),
+			})
+		}
+		return res
+	}
+
 	// removeInfo 删除内存中的 info 信息
 	removeInfo := func(key string) {
 		info, ok := infoMap[key]
@@ -274,6 +324,7 @@ func loopMaintainPlaylist() {
 	// 定时维护一次内存中的数据
 	t := time.NewTicker(maintainDuration)
 	defer t.Stop()
+	defer close(stopped)
 
 	for {
 		select {
@@ -282,7 +333,13 @@ func loopMaintainPlaylist() {
 		case preInfo := <-preMaintainInfoChan:
 			addInfo(preInfo)
 			preChanHandlingGroup.Done()
+		case resChan := <-listReqChan:
+			resChan <- snapshot()
+		case <-stopChan:
+			logs.Info("playlist 维护任务已停止")
+			return
 		}
+		playlistNum.Store(int64(len(infoArr)))
 	}
 
 }
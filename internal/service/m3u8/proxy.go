@@ -39,7 +39,7 @@ func baseCheck(c *gin.Context) (ProxyParams, error) {
 func ProxyPlaylist(c *gin.Context) {
 	params, err := baseCheck(c)
 	if err != nil {
-		logs.Error("代理 m3u8 失败: %v", err.Error())
+		logs.Ctx(c.Request.Context()).Error("代理 m3u8 失败: %v", err.Error())
 		c.String(http.StatusBadRequest, "代理 m3u8 失败, 请检查日志")
 		return
 	}
@@ -72,7 +72,7 @@ func ProxyPlaylist(c *gin.Context) {
 func ProxyTsLink(c *gin.Context) {
 	params, err := baseCheck(c)
 	if err != nil {
-		logs.Error("代理 ts 失败: %v", err)
+		logs.Ctx(c.Request.Context()).Error("代理 ts 失败: %v", err)
 		c.String(http.StatusBadRequest, "代理 ts 失败, 请检查日志")
 		return
 	}
@@ -84,7 +84,7 @@ func ProxyTsLink(c *gin.Context) {
 	}
 
 	okRedirect := func(link string) {
-		logs.Success("重定向 ts: %s", link)
+		logs.Ctx(c.Request.Context()).Success("重定向 ts: %s", link)
 		c.Redirect(http.StatusTemporaryRedirect, link)
 	}
 
@@ -109,7 +109,7 @@ func ProxyTsLink(c *gin.Context) {
 func ProxySubtitle(c *gin.Context) {
 	params, err := baseCheck(c)
 	if err != nil {
-		logs.Error("代理字幕失败: %v", err)
+		logs.Ctx(c.Request.Context()).Error("代理字幕失败: %v", err)
 		c.String(http.StatusBadRequest, "代理字幕失败, 请检查日志")
 		return
 	}
@@ -121,10 +121,10 @@ func ProxySubtitle(c *gin.Context) {
 	}
 
 	proxySubtitle := func(link string) {
-		logs.Info("代理字幕: %s", link)
-		resp, err := https.Get(link).Do()
+		logs.Ctx(c.Request.Context()).Info("代理字幕: %s", link)
+		resp, err := https.Get(link).Context(c.Request.Context()).Do()
 		if err != nil {
-			logs.Error("代理字幕失败: %v", err)
+			logs.Ctx(c.Request.Context()).Error("代理字幕失败: %v", err)
 			c.String(http.StatusInternalServerError, "代理字幕失败, 请检查日志")
 			return
 		}
@@ -135,7 +135,7 @@ func ProxySubtitle(c *gin.Context) {
 		buf := bytess.CommonFixedBuffer()
 		defer buf.PutBack()
 		if _, err = io.CopyBuffer(c.Writer, resp.Body, buf.Bytes()); err != nil {
-			logs.Error("代理字幕失败: %v", err)
+			logs.Ctx(c.Request.Context()).Error("代理字幕失败: %v", err)
 			c.String(http.StatusInternalServerError, "代理字幕失败, 请检查日志")
 			return
 		}
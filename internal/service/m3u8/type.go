@@ -1,6 +1,10 @@
 package m3u8
 
-import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+import (
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+)
 
 // ParentHeadComments 记录文件头注释
 var ParentHeadComments = map[string]struct{}{
@@ -45,6 +49,16 @@ type Info struct {
 	LastUpdate int64
 }
 
+// Summary 播放列表概要信息
+type Summary struct {
+	OpenlistPath string    `json:"openlist_path"` // 资源在 openlist 中的绝对路径
+	TemplateId   string    `json:"template_id"`   // 转码资源模板 id
+	TsNum        int       `json:"ts_num"`        // ts 分片个数
+	SubtitleNum  int       `json:"subtitle_num"`  // 字幕个数
+	LastRead     time.Time `json:"last_read"`     // 客户端最后读取的时间
+	LastUpdate   time.Time `json:"last_update"`   // 程序最后的更新时间
+}
+
 // TsInfo 记录一个 ts 相关信息
 type TsInfo struct {
 	Comments []string // 注释信息
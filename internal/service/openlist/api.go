@@ -1,23 +1,35 @@
 package openlist
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/model"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 )
 
+var (
+	// apiRequests openlist 接口请求数
+	apiRequests = metrics.NewCounterVec("ge2o_openlist_requests_total", "openlist 接口请求数, code 为 openlist 响应的业务状态码", "endpoint", "code")
+
+	// apiDuration openlist 接口请求耗时
+	apiDuration = metrics.NewHistogramVec("ge2o_openlist_request_duration_seconds", "openlist 接口请求耗时", nil, "endpoint")
+)
+
 // FetchResource 请求 openlist 资源 url 直链
-func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
+func FetchResource(ctx context.Context, fi FetchInfo) model.HttpRes[Resource] {
 	if strs.AnyEmpty(fi.Path) {
 		return model.HttpRes[Resource]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
@@ -25,7 +37,7 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 
 	if !fi.UseTranscode {
 		// 请求原画资源
-		res := FetchFsGet(fi.Path, fi.Header)
+		res := FetchFsGet(ctx, fi.Path, fi.Header)
 		if res.Code == http.StatusOK {
 			return model.HttpRes[Resource]{Code: http.StatusOK, Data: Resource{Url: res.Data.RawUrl}}
 		}
@@ -37,13 +49,13 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 		if !fi.TryRawIfTranscodeFail {
 			return model.HttpRes[Resource]{Code: originRes.Code, Msg: originRes.Msg}
 		}
-		logs.Error("请求转码资源失败, 尝试请求原画资源, 原始响应: %v", jsons.FromObject(originRes))
+		logs.Ctx(ctx).Error("请求转码资源失败, 尝试请求原画资源, 原始响应: %v", jsons.FromObject(originRes))
 		fi.UseTranscode = false
-		return FetchResource(fi)
+		return FetchResource(ctx, fi)
 	}
 
 	// 请求转码资源
-	res := FetchFsOther(fi.Path, fi.Header)
+	res := FetchFsOther(ctx, fi.Path, fi.Header)
 	if res.Code != http.StatusOK {
 		return failedAndTryRaw(res)
 	}
@@ -63,7 +75,7 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 		}
 	}
 	if idx == -1 {
-		logs.Error("查找不到指定的格式: %s, 所有可用的格式: [%s]", fi.Format, strings.Join(allFmts, ", "))
+		logs.Ctx(ctx).Error("查找不到指定的格式: %s, 所有可用的格式: [%s]", fi.Format, strings.Join(allFmts, ", "))
 		return failedAndTryRaw(res)
 	}
 
@@ -84,7 +96,7 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 // FetchFsList 请求 openlist "/api/fs/list" 接口
 //
 // 传入 path 与接口的 path 作用一致
-func FetchFsList(path string, header http.Header) model.HttpRes[FsList] {
+func FetchFsList(ctx context.Context, path string, header http.Header) model.HttpRes[FsList] {
 	if strs.AnyEmpty(path) {
 		return model.HttpRes[FsList]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
@@ -93,7 +105,7 @@ func FetchFsList(path string, header http.Header) model.HttpRes[FsList] {
 	defer walkWaiter.Done()
 
 	var res FsList
-	err := Fetch("/api/fs/list", http.MethodPost, header, map[string]any{
+	err := Fetch(ctx, "/api/fs/list", http.MethodPost, header, map[string]any{
 		"refresh":  false,
 		"password": "",
 		"path":     path,
@@ -107,16 +119,21 @@ func FetchFsList(path string, header http.Header) model.HttpRes[FsList] {
 // FetchFsGet 请求 openlist "/api/fs/get" 接口
 //
 // 传入 path 与接口的 path 作用一致
-func FetchFsGet(path string, header http.Header) model.HttpRes[FsGet] {
+func FetchFsGet(ctx context.Context, path string, header http.Header) model.HttpRes[FsGet] {
 	if strs.AnyEmpty(path) {
 		return model.HttpRes[FsGet]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
 
+	// 该接口会访问网盘, 需要限流
+	if err := waitGlobalLimit(ctx); err != nil {
+		return model.HttpRes[FsGet]{Code: http.StatusTooManyRequests, Msg: fmt.Sprintf("FsGet 请求失败: %v", err)}
+	}
+
 	walkWaiter.Add(1)
 	defer walkWaiter.Done()
 
 	var res FsGet
-	err := Fetch("/api/fs/get", http.MethodPost, header, map[string]any{
+	err := Fetch(ctx, "/api/fs/get", http.MethodPost, header, map[string]any{
 		"refresh":  false,
 		"password": "",
 		"path":     path,
@@ -130,16 +147,21 @@ func FetchFsGet(path string, header http.Header) model.HttpRes[FsGet] {
 // FetchFsOther 请求 openlist "/api/fs/other" 接口
 //
 // 传入 path 与接口的 path 作用一致
-func FetchFsOther(path string, header http.Header) model.HttpRes[FsOther] {
+func FetchFsOther(ctx context.Context, path string, header http.Header) model.HttpRes[FsOther] {
 	if strs.AnyEmpty(path) {
 		return model.HttpRes[FsOther]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
 
+	// 该接口会访问网盘, 需要限流
+	if err := waitGlobalLimit(ctx); err != nil {
+		return model.HttpRes[FsOther]{Code: http.StatusTooManyRequests, Msg: fmt.Sprintf("FsOther 请求失败: %v", err)}
+	}
+
 	walkWaiter.Add(1)
 	defer walkWaiter.Done()
 
 	var res FsOther
-	err := Fetch("/api/fs/other", http.MethodPost, header, map[string]any{
+	err := Fetch(ctx, "/api/fs/other", http.MethodPost, header, map[string]any{
 		"method":   "video_preview",
 		"password": "",
 		"path":     path,
@@ -151,13 +173,79 @@ func FetchFsOther(path string, header http.Header) model.HttpRes[FsOther] {
 }
 
 // Fetch 请求 openlist api, 响应封装在 v 指针指向的结构中
-func Fetch(uri, method string, header http.Header, body map[string]any, v any) error {
-	host := config.C.Openlist.Host
-	token := config.C.Openlist.Token
-	if strs.AnyEmpty(host, token) {
+//
+// 配置了多个 openlist 实例时, 按照健康状况依次请求,
+// 实例不可用时会自动切换到下一个实例重试, ctx 被取消后不再重试
+func Fetch(ctx context.Context, uri, method string, header http.Header, body map[string]any, v any) error {
+	instances := sortByHealth(config.C().Openlist.AllInstances())
+	if len(instances) == 0 {
 		return fmt.Errorf("openlist.host 或 openlist.token 配置为空")
 	}
 
+	var data json.RawMessage
+	var err error
+	errs := make([]string, 0, len(instances))
+	for i, ins := range instances {
+		var retry bool
+		data, retry, err = fetchInstance(ctx, ins, uri, method, header, body)
+		if err == nil || !retry {
+			break
+		}
+		errs = append(errs, fmt.Sprintf("[%s] %v", ins.Host, err))
+		if i < len(instances)-1 {
+			logs.Ctx(ctx).Warn("openlist 实例 [%s] 请求失败, 尝试下一个实例: %v", ins.Host, err)
+		}
+	}
+	if err != nil {
+		if len(errs) > 1 {
+			return fmt.Errorf("所有 openlist 实例均请求失败: %s", strings.Join(errs, "; "))
+		}
+		return err
+	}
+
+	// 如果 v 参数为不为 nil 的指针, 写入响应数据
+	vf := reflect.ValueOf(v)
+	if vf.Kind() != reflect.Ptr || vf.IsNil() {
+		return nil
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("Fetch 请求响应数据解析失败: %v, 响应内容: %s", err, string(data))
+	}
+	return nil
+}
+
+// fetchInstance 请求指定的 openlist 实例, 并记录实例的健康状态
+//
+// 第二个返回值标记错误是否由实例不可用导致, 此时可以切换其他实例重试
+func fetchInstance(ctx context.Context, ins *config.OpenlistInstance, uri, method string, header http.Header, body map[string]any) (json.RawMessage, bool, error) {
+	if strs.AnyEmpty(ins.Host, ins.Token) {
+		return nil, true, fmt.Errorf("openlist.host 或 openlist.token 配置为空")
+	}
+
+	start := time.Now()
+	data, code, retry, err := doFetch(ctx, ins, uri, method, header, body)
+	cost := time.Since(start)
+
+	// 请求被调用方取消, 与实例的健康状况无关
+	if ctx.Err() != nil {
+		return nil, false, fmt.Errorf("Fetch 请求已取消: %w", ctx.Err())
+	}
+	getHealth(ins.Host).record(ins.Host, cost, retry)
+
+	// 记录统计指标, 请求或解析失败时响应码记为 error
+	endpoint, codeLabel := strings.TrimPrefix(uri, "/api/"), "error"
+	if code != 0 {
+		codeLabel = strconv.Itoa(code)
+	}
+	apiRequests.Inc(endpoint, codeLabel)
+	apiDuration.Observe(cost.Seconds(), endpoint)
+	return data, retry, err
+}
+
+// doFetch 发出请求, 校验响应状态后返回响应数据
+//
+// 第二个返回值为 openlist 响应的业务状态码, 请求或解析失败时为 0
+func doFetch(ctx context.Context, ins *config.OpenlistInstance, uri, method string, header http.Header, body map[string]any) (json.RawMessage, int, bool, error) {
 	// 1 发出请求
 	if header == nil {
 		header = make(http.Header)
@@ -165,35 +253,28 @@ func Fetch(uri, method string, header http.Header, body map[string]any, v any) e
 		header = header.Clone()
 	}
 	header.Set("Content-Type", "application/json;charset=utf-8")
-	header.Set("Authorization", token)
+	header.Set("Authorization", ins.Token)
 
-	resp, err := https.Request(method, host+uri).Header(header).Body(https.MapBody(body)).Do()
+	resp, err := https.Request(method, ins.Host+uri).Context(ctx).Header(header).Body(https.MapBody(body)).Do()
 	if err != nil {
-		return fmt.Errorf("Fetch 请求失败: %v", err)
+		return nil, 0, true, fmt.Errorf("Fetch 请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// 2 检测响应状态是否正常
 	resBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("Fetch 请求读取响应失败: %v", err)
+		return nil, 0, true, fmt.Errorf("Fetch 请求读取响应失败: %v", err)
 	}
 
 	var res RemoteCommonResult
 	if err = json.Unmarshal(resBytes, &res); err != nil {
-		return fmt.Errorf("Fetch 请求响应解析失败: %v, 响应内容: %v", err, string(resBytes))
+		return nil, 0, true, fmt.Errorf("Fetch 请求响应解析失败: %v, 响应内容: %v", err, string(resBytes))
 	}
 	if res.Code != http.StatusOK {
-		return fmt.Errorf("Fetch 请求响应状态异常: %d, 消息: %s", res.Code, res.Message)
-	}
-
-	// 3 如果 v 参数为不为 nil 的指针, 写入响应数据
-	vf := reflect.ValueOf(v)
-	if vf.Kind() != reflect.Ptr || vf.IsNil() {
-		return nil
+		// 令牌失效视为实例不可用, 其余业务异常直接返回
+		retry := res.Code == http.StatusUnauthorized
+		return nil, res.Code, retry, fmt.Errorf("Fetch 请求响应状态异常: %d, 消息: %s", res.Code, res.Message)
 	}
-	if err = json.Unmarshal(res.Data, v); err != nil {
-		return fmt.Errorf("Fetch 请求响应数据解析失败: %v, 响应内容: %s", err, string(res.Data))
-	}
-	return nil
+	return res.Data, res.Code, false, nil
 }
@@ -0,0 +1,175 @@
+package openlist
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+const (
+	// HealthDecay 健康指标的指数加权平均衰减系数, 越大越看重最近的请求
+	HealthDecay = 0.3
+
+	// UnhealthyErrRate 错误率超过该值时, 实例被标记为不健康
+	UnhealthyErrRate = 0.5
+
+	// UnhealthyCooldown 实例被标记为不健康后, 在该时间内会被排到最后
+	UnhealthyCooldown = time.Second * 30
+
+	// HealthLogInterval 输出健康状态表的时间间隔
+	HealthLogInterval = time.Minute * 10
+)
+
+// instanceHealth 单个 openlist 实例的健康状态
+type instanceHealth struct {
+	mu sync.Mutex
+
+	// latency 请求耗时的加权平均值, 单位: 毫秒
+	latency float64
+
+	// errRate 请求错误率的加权平均值
+	errRate float64
+
+	// total, failed 请求总数与失败数
+	total, failed int64
+
+	// unhealthyAt 最近一次被标记为不健康的时间
+	unhealthyAt time.Time
+}
+
+// healthMap 实例地址 => 健康状态
+//
+// 以地址作为 key, 配置热重载后依旧可以保留健康状态
+var healthMap sync.Map
+
+func init() {
+	go loopLogHealth()
+}
+
+// getHealth 获取实例的健康状态
+func getHealth(host string) *instanceHealth {
+	h, _ := healthMap.LoadOrStore(host, new(instanceHealth))
+	return h.(*instanceHealth)
+}
+
+// record 记录一次请求结果
+func (h *instanceHealth) record(host string, cost time.Duration, failed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	errVal := 0.0
+	if failed {
+		errVal = 1
+		h.failed++
+	}
+	ms := float64(cost.Milliseconds())
+	if h.total == 0 {
+		h.latency, h.errRate = ms, errVal
+	} else {
+		h.latency = HealthDecay*ms + (1-HealthDecay)*h.latency
+		h.errRate = HealthDecay*errVal + (1-HealthDecay)*h.errRate
+	}
+	h.total++
+
+	wasHealthy := time.Since(h.unhealthyAt) > UnhealthyCooldown
+	if failed && h.errRate >= UnhealthyErrRate {
+		if wasHealthy {
+			logs.Warn("openlist 实例 [%s] 错误率过高 (%.2f), 标记为不健康", host, h.errRate)
+		}
+		h.unhealthyAt = time.Now()
+		return
+	}
+	if !failed && !wasHealthy && h.errRate < UnhealthyErrRate {
+		logs.Success("openlist 实例 [%s] 恢复健康", host)
+		h.unhealthyAt = time.Time{}
+	}
+}
+
+// healthy 判断实例当前是否健康
+func (h *instanceHealth) healthy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Since(h.unhealthyAt) > UnhealthyCooldown
+}
+
+// score 实例的健康评分, 值越小越优先
+func (h *instanceHealth) score() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.latency * (1 + 10*h.errRate)
+}
+
+// sortByHealth 按照健康状况对实例进行排序
+//
+// 健康的实例优先, 其次按照评分升序, 评分相同时保持配置顺序
+func sortByHealth(instances []*config.OpenlistInstance) []*config.OpenlistInstance {
+	res := slices.Clone(instances)
+	if len(res) < 2 {
+		return res
+	}
+
+	type rank struct {
+		healthy bool
+		score   float64
+	}
+	ranks := make(map[string]rank, len(res))
+	for _, ins := range res {
+		h := getHealth(ins.Host)
+		ranks[ins.Host] = rank{healthy: h.healthy(), score: h.score()}
+	}
+
+	slices.SortStableFunc(res, func(a, b *config.OpenlistInstance) int {
+		ra, rb := ranks[a.Host], ranks[b.Host]
+		if ra.healthy != rb.healthy {
+			if ra.healthy {
+				return -1
+			}
+			return 1
+		}
+		switch {
+		case ra.score < rb.score:
+			return -1
+		case ra.score > rb.score:
+			return 1
+		}
+		return 0
+	})
+	return res
+}
+
+// HealthTable 生成所有已配置实例的健康状态表
+func HealthTable() string {
+	sb := strings.Builder{}
+	sb.WriteString("openlist 实例健康状态:")
+	for _, ins := range config.C().Openlist.AllInstances() {
+		h := getHealth(ins.Host)
+		h.mu.Lock()
+		status := "健康"
+		if time.Since(h.unhealthyAt) <= UnhealthyCooldown {
+			status = "不健康"
+		}
+		sb.WriteString(fmt.Sprintf(
+			"\n[%s] 状态: %s, 平均耗时: %.0fms, 错误率: %.2f, 请求数: %d, 失败数: %d",
+			ins.Host, status, h.latency, h.errRate, h.total, h.failed,
+		))
+		h.mu.Unlock()
+	}
+	return sb.String()
+}
+
+// loopLogHealth 配置了多个实例时, 定时输出健康状态表
+func loopLogHealth() {
+	ticker := time.NewTicker(HealthLogInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if config.C() == nil || len(config.C().Openlist.AllInstances()) < 2 {
+			continue
+		}
+		logs.Info("%s", HealthTable())
+	}
+}
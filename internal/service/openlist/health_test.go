@@ -0,0 +1,127 @@
+package openlist_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+)
+
+func TestFetchFailover(t *testing.T) {
+	var badHits, goodHits atomic.Int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		badHits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("<html>502 Bad Gateway</html>"))
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		goodHits.Add(1)
+		if r.Header.Get("Authorization") != "mirror-token" {
+			w.Write([]byte(`{"code":401,"message":"token is invalidated"}`))
+			return
+		}
+		w.Write([]byte(`{"code":200,"message":"success","data":{"total":1,"content":[{"name":"a.mkv"}]}}`))
+	}))
+	defer good.Close()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := fmt.Sprintf(`emby:
+  host: http://127.0.0.1:8096
+openlist:
+  host: %s
+  token: primary-token
+  instances:
+    - host: %s
+      token: mirror-token
+`, bad.URL, good.URL)
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// 主实例不可用, 自动切换到镜像实例
+	res := openlist.FetchFsList(context.Background(), "/", nil)
+	if res.Code != http.StatusOK || res.Data.Total != 1 {
+		t.Fatalf("期望切换实例后请求成功, 实际: %+v", res)
+	}
+	if badHits.Load() != 1 || goodHits.Load() != 1 {
+		t.Fatalf("请求次数异常, bad: %d, good: %d", badHits.Load(), goodHits.Load())
+	}
+
+	// 主实例被标记为不健康, 后续请求优先使用镜像实例
+	res = openlist.FetchFsList(context.Background(), "/", nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("请求失败: %+v", res)
+	}
+	if badHits.Load() != 1 || goodHits.Load() != 2 {
+		t.Fatalf("期望跳过不健康的实例, bad: %d, good: %d", badHits.Load(), goodHits.Load())
+	}
+
+	table := openlist.HealthTable()
+	if !strings.Contains(table, bad.URL+"] 状态: 不健康") || !strings.Contains(table, good.URL+"] 状态: 健康") {
+		t.Fatalf("健康状态表异常:\n%s", table)
+	}
+}
+
+func TestFetchCanceled(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		// 读取完请求体后, 服务端才能感知到客户端断开连接
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"code":200,"message":"success","data":{"total":0,"content":[]}}`))
+	}))
+	defer mirror.Close()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := fmt.Sprintf(`emby:
+  host: http://127.0.0.1:8096
+openlist:
+  host: %s
+  token: token
+  instances:
+    - host: %s
+      token: token
+`, srv.URL, mirror.URL)
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// 调用方取消请求后, 不再切换实例重试
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	res := openlist.FetchFsList(ctx, "/", nil)
+	if res.Code == http.StatusOK {
+		t.Fatalf("期望请求被取消, 实际: %+v", res)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("取消后不应继续请求其他实例, 请求次数: %d", hits.Load())
+	}
+
+	// 取消的请求不影响实例的健康状态
+	table := openlist.HealthTable()
+	if !strings.Contains(table, srv.URL+"] 状态: 健康") {
+		t.Fatalf("健康状态表异常:\n%s", table)
+	}
+}
@@ -0,0 +1,46 @@
+package openlist
+
+import (
+	"context"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/ratelimit"
+)
+
+// 限流的范围
+const (
+	LimitScopeGlobal = "global" // 全局限流
+	LimitScopeClient = "client" // 按客户端 ip 或用户限流
+)
+
+var (
+	// globalLimiter 全局限流器, 所有获取直链的请求共用
+	globalLimiter ratelimit.Group
+
+	// rateLimited 被限流的请求数
+	rateLimited = metrics.NewCounterVec("ge2o_rate_limited_total", "获取直链时被限流的请求数", "scope")
+)
+
+// WaitLimit 从限流器中获取令牌, 令牌不足时在 rate-limit.max-wait 内排队等待
+//
+// 未开启限流时直接返回 nil
+func WaitLimit(ctx context.Context, scope string, ls ...*ratelimit.Limiter) error {
+	rl := config.C().RateLimit
+	if !rl.Enable {
+		return nil
+	}
+	if err := ratelimit.Wait(ctx, rl.MaxWaitDuration(), ls...); err != nil {
+		rateLimited.Inc(scope)
+		logs.Ctx(ctx).Warn("获取直链被限流, 范围: %s, err: %v", scope, err)
+		return err
+	}
+	return nil
+}
+
+// waitGlobalLimit 请求会访问网盘的 openlist 接口前, 进行全局限流
+func waitGlobalLimit(ctx context.Context) error {
+	g := config.C().RateLimit.Global
+	return WaitLimit(ctx, LimitScopeGlobal, globalLimiter.Get("", g.PerMinute, g.Burst))
+}
@@ -1,51 +1,237 @@
 package localtree
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
 )
 
 // DirName 存放目录树的本地目录名称
 const DirName = "openlist-local-tree"
 
+var (
+	// startOnce 保证同步循环只会被启动一次
+	startOnce sync.Once
+
+	// resetChan 通知同步循环按照最新的配置重置定时器
+	resetChan = make(chan struct{}, 1)
+
+	// triggerChan 通知同步循环立即执行一次同步
+	triggerChan = make(chan struct{}, 1)
+
+	// started 标记同步循环是否已经启动
+	started atomic.Bool
+
+	// rootCtx 程序退出时被取消, 用于中断正在进行的同步
+	rootCtx = context.Background()
+
+	// loopDone 同步循环退出时关闭
+	loopDone = make(chan struct{})
+
+	// status 最近一次同步的状态
+	status   Status
+	statusMu sync.RWMutex
+)
+
+// Status 目录树同步状态
+type Status struct {
+	Enable    bool      `json:"enable"`     // 目录树是否启用
+	Syncing   bool      `json:"syncing"`    // 是否正在同步
+	LastStart time.Time `json:"last_start"` // 最近一次同步的开始时间
+	LastCost  string    `json:"last_cost"`  // 最近一次同步的耗时
+	LastErr   string    `json:"last_err"`   // 最近一次同步的错误信息
+	Total     int       `json:"total"`      // 最近一次同步的文件总数
+	Added     int       `json:"added"`      // 最近一次同步的新增数
+	Deleted   int       `json:"deleted"`    // 最近一次同步的删除数
+	NextSync  time.Time `json:"next_sync"`  // 下一次定时同步的时间
+}
+
+var (
+	// syncDuration 目录树同步耗时
+	syncDuration = metrics.NewHistogramVec("ge2o_localtree_sync_duration_seconds", "本地目录树同步耗时", []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}, "result")
+
+	// syncFiles 目录树同步的文件变更数
+	syncFiles = metrics.NewCounterVec("ge2o_localtree_sync_files_total", "本地目录树同步时新增与删除的文件数", "op")
+)
+
+// recordSync 记录同步的统计指标
+func recordSync(cost time.Duration, added, deleted int, err error) {
+	if err != nil {
+		syncDuration.Observe(cost.Seconds(), "error")
+		return
+	}
+	syncDuration.Observe(cost.Seconds(), "success")
+	syncFiles.Add(float64(added), "added")
+	syncFiles.Add(float64(deleted), "deleted")
+}
+
+// GetStatus 获取目录树的同步状态
+func GetStatus() Status {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	s := status
+	s.Enable = config.C().Openlist.LocalTreeGen.Enable
+	return s
+}
+
+// updateStatus 更新目录树的同步状态
+func updateStatus(fn func(s *Status)) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	fn(&status)
+}
+
+// TriggerSync 触发一次立即同步
+//
+// 同步任务会在同步循环中异步执行, 已有排队中的任务时直接返回
+func TriggerSync() error {
+	if !config.C().Openlist.LocalTreeGen.Enable || !started.Load() {
+		return errors.New("本地目录树未启用")
+	}
+	select {
+	case triggerChan <- struct{}{}:
+		return nil
+	default:
+		return errors.New("已有同步任务正在排队")
+	}
+}
+
 // Init 根据配置文件, 初始化本地目录树
-func Init() error {
-	// 判断配置是否开启
-	if !config.C.Openlist.LocalTreeGen.Enable {
+//
+// 配置重载时, 若目录树由关闭变为开启, 会自动启动同步;
+// 若刷新间隔发生变更, 会以新的间隔重新计时;
+// ctx 被取消时, 中断正在进行的同步并退出同步循环
+func Init(ctx context.Context) error {
+	rootCtx = ctx
+	config.OnReload(apply)
+	apply()
+	return nil
+}
+
+// Wait 等待同步循环退出, 需要在 Init 传入的 ctx 被取消后调用
+//
+// 正在写入的文件会在写入完成后退出, 等待超时返回错误
+func Wait(ctx context.Context) error {
+	if !started.Load() {
+		return nil
+	}
+	select {
+	case <-loopDone:
 		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待目录树同步任务退出超时: %w", ctx.Err())
 	}
+}
 
-	dirAbs := filepath.Join(config.BasePath, DirName)
+// apply 根据当前配置启动同步循环或重置定时器
+func apply() {
+	// 判断配置是否开启
+	if !config.C().Openlist.LocalTreeGen.Enable || rootCtx.Err() != nil {
+		return
+	}
 
-	s := NewSynchronizer(dirAbs, 30)
-	go startSync(s)
+	justStarted := false
+	startOnce.Do(func() {
+		dirAbs := filepath.Join(config.BasePath, DirName)
+		s := NewSynchronizer(dirAbs, 30)
+		go startSync(rootCtx, s)
+		started.Store(true)
+		justStarted = true
+	})
+	if justStarted {
+		return
+	}
 
-	return nil
+	select {
+	case resetChan <- struct{}{}:
+	default:
+	}
 }
 
 // startSync 立即同步一次目录树, 并开始定时扫描同步变更
-func startSync(s *Synchronizer) {
+//
+// 目录树配置被关闭时, 定时器照常运行但跳过同步
+func startSync(ctx context.Context, s *Synchronizer) {
+	defer close(loopDone)
+
 	doSync := func() {
+		if !config.C().Openlist.LocalTreeGen.Enable || ctx.Err() != nil {
+			return
+		}
 		logf(colors.Blue, "开始同步")
 		start := time.Now()
-		total, added, deleted, err := s.Sync()
+		updateStatus(func(st *Status) {
+			st.Syncing, st.LastStart = true, start
+		})
+		total, added, deleted, err := s.Sync(ctx)
+		cost := time.Since(start)
+		recordSync(cost, added, deleted, err)
+		updateStatus(func(st *Status) {
+			st.Syncing, st.LastCost, st.LastErr = false, cost.String(), ""
+			if err != nil {
+				st.LastErr = err.Error()
+				return
+			}
+			st.Total, st.Added, st.Deleted = total, added, deleted
+		})
+		if err != nil && ctx.Err() != nil {
+			logf(colors.Yellow, "程序正在退出, 同步已中断, 耗时: %v", cost)
+			return
+		}
 		if err != nil {
 			logf(colors.Red, "同步失败: %v", err)
 			return
 		}
-		logf(colors.Green, "同步完成, 总数: %d, 新增: %d, 删除: %d, 耗时: %v", total, added, deleted, time.Since(start))
+		logf(colors.Green, "同步完成, 总数: %d, 新增: %d, 删除: %d, 耗时: %v", total, added, deleted, cost)
 	}
 	doSync()
 
-	d := time.Minute * time.Duration(config.C.Openlist.LocalTreeGen.RefreshInterval)
-	timer := time.NewTicker(d)
-	for range timer.C {
-		doSync()
+	interval := func() time.Duration {
+		ri := config.C().Openlist.LocalTreeGen.RefreshInterval
+		if ri <= 0 {
+			// 目录树关闭时不会校验刷新间隔, 这里兜底避免定时器空转
+			ri = 1
+		}
+		return time.Minute * time.Duration(ri)
+	}
+	// resetTimer 按照当前配置重新计时
+	//
+	// go1.23 之后, 重置定时器时不再需要手动排空通道
+	var timer *time.Timer
+	resetTimer := func() {
+		d := interval()
+		if timer == nil {
+			timer = time.NewTimer(d)
+		} else {
+			timer.Reset(d)
+		}
+		updateStatus(func(st *Status) { st.NextSync = time.Now().Add(d) })
+	}
+	resetTimer()
+
+	for {
+		select {
+		case <-timer.C:
+			doSync()
+		case <-triggerChan:
+			logf(colors.Blue, "收到手动同步请求")
+			doSync()
+		case <-resetChan:
+			logf(colors.Blue, "配置已更新, 刷新间隔: %v", interval())
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		resetTimer()
 	}
 }
 
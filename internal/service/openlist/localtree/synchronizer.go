@@ -18,6 +18,9 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// TmpFileSuffix 写入文件时使用的临时文件后缀
+const TmpFileSuffix = ".ge2o-tmp"
+
 // Synchronizer 同步远程 openlist 信息为本地磁盘目录树
 type Synchronizer struct {
 	// snapshot 同步过程中, 实时维护快照信息
@@ -52,7 +55,10 @@ func NewSynchronizer(baseDir string, pageSize int) *Synchronizer {
 }
 
 // Sync 触发一次同步操作
-func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
+//
+// ctx 被取消时, 不再处理新的任务, 等待正在写入的文件完成后返回错误,
+// 此时不会删除本地的任何文件
+func (s *Synchronizer) Sync(ctx context.Context) (total, added, deleted int, err error) {
 	if err := s.InitSnapshot(); err != nil {
 		return 0, 0, 0, fmt.Errorf("初始化快照异常: %w", err)
 	}
@@ -60,7 +66,7 @@ func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
 	// 初始化状态
 	s.toSyncTasks = make(chan []FileTask, 1024)
 	okTaskChan := make(chan FileTask, 1024)
-	s.eg, s.ctx = errgroup.WithContext(context.Background())
+	s.eg, s.ctx = errgroup.WithContext(ctx)
 
 	// 读取根目录放置到任务通道中
 	s.activeTaskCount = 0
@@ -81,6 +87,9 @@ func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
 	if err := s.eg.Wait(); err != nil {
 		return 0, 0, 0, fmt.Errorf("同步异常: %w", err)
 	}
+	if err := ctx.Err(); err != nil {
+		return 0, 0, 0, fmt.Errorf("同步被取消: %w", err)
+	}
 	return
 }
 
@@ -123,6 +132,10 @@ func (s *Synchronizer) InitSnapshot() error {
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
+			// 清理程序异常退出时残留的临时文件
+			if strings.HasSuffix(base, TmpFileSuffix) {
+				os.Remove(path)
+			}
 			return nil
 		}
 
@@ -139,7 +152,7 @@ func (s *Synchronizer) InitSnapshot() error {
 
 // walkDir2SyncTasks 分页遍历 openlist 指定前缀目录下的文件, 加入到任务通道中
 func (s *Synchronizer) walkDir2SyncTasks(prefix string) error {
-	walker := openlist.WalkFsList(prefix, s.pageSize)
+	walker := openlist.WalkFsList(s.ctx, prefix, s.pageSize)
 	var page openlist.FsList
 	var err error
 
@@ -228,8 +241,17 @@ func (s *Synchronizer) handleSyncTasks(okTaskChan chan<- FileTask) {
 			return fmt.Errorf("初始化父目录异常 [%s]: %w", localAbsPath, err)
 		}
 
-		// 写入文件
-		return writer.Write(*task, localAbsPath)
+		// 先写入隐藏的临时文件再重命名, 避免程序中途退出时留下不完整的文件
+		tmpPath := filepath.Join(filepath.Dir(localAbsPath), "."+filepath.Base(localAbsPath)+TmpFileSuffix)
+		if err := writer.Write(*task, tmpPath); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		if err := os.Rename(tmpPath, localAbsPath); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("重命名临时文件异常 [%s]: %w", localAbsPath, err)
+		}
+		return nil
 	}
 
 	// handleTasks 处理任务, 将新增的文件写入本地, 任务处理完成后写入 okTaskChan
@@ -240,7 +262,7 @@ func (s *Synchronizer) handleSyncTasks(okTaskChan chan<- FileTask) {
 				return nil
 			default:
 				// 根据用户配置忽略特定文件和目录
-				cfg := config.C.Openlist.LocalTreeGen
+				cfg := config.C().Openlist.LocalTreeGen
 				if !cfg.IsValidPrefix(task.Path) {
 					continue
 				}
@@ -260,7 +282,11 @@ func (s *Synchronizer) handleSyncTasks(okTaskChan chan<- FileTask) {
 				}
 
 				// 当前任务写入 okTaskChan
-				okTaskChan <- task
+				select {
+				case okTaskChan <- task:
+				case <-s.ctx.Done():
+					return nil
+				}
 			}
 		}
 		return nil
@@ -313,6 +339,11 @@ func (s *Synchronizer) updateLocalTree(okTaskChan <-chan FileTask, total, added,
 		}
 	}
 
+	// 同步被取消时, 快照不完整, 不能删除本地文件
+	if s.ctx.Err() != nil {
+		return
+	}
+
 	toDelete := make([]string, 0, 1<<6)
 
 	// 统计并删除本地过期文件
@@ -325,7 +356,7 @@ func (s *Synchronizer) updateLocalTree(okTaskChan <-chan FileTask, total, added,
 		toDelete = append(toDelete, filepath.Join(s.baseDir, path))
 	}
 
-	maxCount := config.C.Openlist.LocalTreeGen.AutoRemoveMaxCount
+	maxCount := config.C().Openlist.LocalTreeGen.AutoRemoveMaxCount
 	if len(toDelete) > maxCount {
 		logf(colors.Yellow, "过期文件数量 [%d] 超出最大限制 [%d], 跳过删除操作", len(toDelete), maxCount)
 		return
@@ -1,6 +1,8 @@
 package localtree_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist/localtree"
@@ -22,3 +24,25 @@ func TestSynchronizer_InitSnapshot(t *testing.T) {
 		})
 	}
 }
+
+func TestSynchronizer_InitSnapshotCleanTmp(t *testing.T) {
+	dir := t.TempDir()
+	tmpFile := filepath.Join(dir, ".movie.mp4"+localtree.TmpFileSuffix)
+	keepFile := filepath.Join(dir, "movie.mp4")
+	for _, f := range []string{tmpFile, keepFile} {
+		if err := os.WriteFile(f, []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := localtree.NewSynchronizer(dir, 50)
+	if err := s.InitSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tmpFile); !os.IsNotExist(err) {
+		t.Errorf("残留的临时文件未被清理: %v", err)
+	}
+	if _, err := os.Stat(keepFile); err != nil {
+		t.Errorf("正常文件被误删: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package localtree
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -72,7 +73,7 @@ var (
 
 // LoadTaskWriter 根据文件容器加载 TaskWriter
 func LoadTaskWriter(container string) TaskWriter {
-	cfg := config.C.Openlist.LocalTreeGen
+	cfg := config.C().Openlist.LocalTreeGen
 	if cfg.IsVirtual(container) {
 		return &vw
 	}
@@ -98,7 +99,7 @@ func (vw *VirtualWriter) Path(path string) string {
 func (vw *VirtualWriter) Write(task FileTask, localPath string) error {
 	// 默认写入时长 3 小时
 	dftDuration := time.Hour * 3
-	if !config.C.Openlist.LocalTreeGen.FFmpegEnable {
+	if !config.C().Openlist.LocalTreeGen.FFmpegEnable {
 		return os.WriteFile(localPath, mp4s.GenWithDuration(dftDuration), os.ModePerm)
 	}
 
@@ -129,7 +130,7 @@ func (sw *StrmWriter) OpenlistPath(task FileTask) string {
 
 	return fmt.Sprintf(
 		"%s/d/%s?sign=%s",
-		config.C.Openlist.Host,
+		config.C().Openlist.Host,
 		strings.Join(segs, "/"),
 		task.Sign,
 	)
@@ -158,7 +159,7 @@ func (mw *MusicWriter) Path(path string) string {
 
 // Write 将文件信息写入到本地文件系统中
 func (mw *MusicWriter) Write(task FileTask, localPath string) error {
-	if !config.C.Openlist.LocalTreeGen.FFmpegEnable {
+	if !config.C().Openlist.LocalTreeGen.FFmpegEnable {
 		// 必须开启 ffmpeg 才能生成, 改用 strm 替代
 		return sw.Write(task, localPath)
 	}
@@ -208,8 +209,9 @@ func (rw *RawWriter) Write(task FileTask, localPath string) error {
 	rw.mu.Lock()
 	defer rw.mu.Unlock()
 
+	// 正在写入的文件需要完整写完, 不跟随同步任务取消
 	header := http.Header{"User-Agent": []string{"libmpv"}}
-	res := openlist.FetchFsGet(task.Path, header)
+	res := openlist.FetchFsGet(context.Background(), task.Path, header)
 	if res.Code != http.StatusOK {
 		return fmt.Errorf("请求 openlist 文件失败: %s", res.Msg)
 	}
@@ -1,6 +1,7 @@
 package openlist
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -26,7 +27,7 @@ var walkWaiter = sync.WaitGroup{}
 // FetchFsList 请求 openlist "/api/fs/list" 接口, 支持分页
 //
 // 传入 path 与接口的 path 作用一致
-func WalkFsList(path string, perPage int) *Walker[FsList] {
+func WalkFsList(ctx context.Context, path string, perPage int) *Walker[FsList] {
 	w := Walker[FsList]{curPage: 1}
 
 	w.Next = func() (FsList, error) {
@@ -36,7 +37,7 @@ func WalkFsList(path string, perPage int) *Walker[FsList] {
 		walkWaiter.Wait()
 
 		var res FsList
-		err := Fetch("/api/fs/list", http.MethodPost, nil, map[string]any{
+		err := Fetch(ctx, "/api/fs/list", http.MethodPost, nil, map[string]any{
 			"refresh":  false,
 			"password": "",
 			"path":     path,
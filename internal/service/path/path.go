@@ -1,6 +1,7 @@
 package path
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -21,11 +22,13 @@ type OpenlistPathRes struct {
 	Path string
 
 	// Range 遍历所有 Openlist 根路径生成的子路径
-	Range func() ([]string, error)
+	Range func(ctx context.Context) ([]string, error)
 }
 
 // Emby2Openlist Emby 资源路径转 Openlist 资源路径
-func Emby2Openlist(embyPath string) OpenlistPathRes {
+//
+// 使用 upstream 的 mount-path 以及路径映射配置进行转换, ctx 用于输出附带请求 id 的日志
+func Emby2Openlist(ctx context.Context, upstream *config.Emby, embyPath string) OpenlistPathRes {
 	pathRoutes := strings.Builder{}
 	pathRoutes.WriteString("[")
 	pathRoutes.WriteString("\n【原始路径】 => " + embyPath)
@@ -36,24 +39,25 @@ func Emby2Openlist(embyPath string) OpenlistPathRes {
 	embyPath = urls.TransferSlash(embyPath)
 	pathRoutes.WriteString("\n\n【Windows 反斜杠转换】 => " + embyPath)
 
-	embyMount := config.C.Emby.MountPath
+	embyMount := upstream.MountPath
 	openlistFilePath := strings.TrimPrefix(embyPath, embyMount)
 	pathRoutes.WriteString("\n\n【移除 mount-path】 => " + openlistFilePath)
 
-	if mapPath, ok := config.C.Path.MapEmby2Openlist(openlistFilePath); ok {
+	if mapPath, rule := upstream.Path.MatchEmby2Openlist(openlistFilePath); rule != nil {
 		openlistFilePath = mapPath
-		pathRoutes.WriteString("\n\n【命中 emby2openlist 映射】 => " + openlistFilePath)
+		pathRoutes.WriteString("\n\n【命中 emby2openlist 映射: " + rule.String() + "】 => " + openlistFilePath)
+		pathRoutes.WriteString("\n(如命中错误, 请将正确的映射配置前移)")
 	}
 	pathRoutes.WriteString("\n]")
-	logs.Tip("embyPath 转换路径: %s", pathRoutes.String())
+	logs.Ctx(ctx).Tip("embyPath 转换路径: %s", pathRoutes.String())
 
-	rangeFunc := func() ([]string, error) {
+	rangeFunc := func(ctx context.Context) ([]string, error) {
 		filePath, err := SplitFromSecondSlash(openlistFilePath)
 		if err != nil {
 			return nil, fmt.Errorf("openlistFilePath 解析异常: %s, error: %v", openlistFilePath, err)
 		}
 
-		res := openlist.FetchFsList("/", nil)
+		res := openlist.FetchFsList(ctx, "/", nil)
 		if res.Code != http.StatusOK {
 			return nil, fmt.Errorf("请求 openlist fs list 接口异常: %s", res.Msg)
 		}
@@ -1,11 +1,17 @@
 package https_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"path"
 	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 )
 
 func TestRelativeRedirect(t *testing.T) {
@@ -19,3 +25,26 @@ func TestRelativeRedirect(t *testing.T) {
 	loc = fmt.Sprintf("%s://%s%s/%s", req.URL.Scheme, req.URL.Host, dirPath, loc)
 	log.Println(loc)
 }
+
+func TestRequestContext(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	start := time.Now()
+	_, err := https.Get(srv.URL).Context(ctx).Do()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望请求被上下文中断, 实际: %v", err)
+	}
+	if cost := time.Since(start); cost > time.Second {
+		t.Fatalf("请求未及时中断, 耗时: %v", cost)
+	}
+}
@@ -2,6 +2,7 @@ package https
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -25,6 +26,9 @@ type RequestHolder struct {
 
 	// redirect 是否自动重定向
 	redirect bool
+
+	// ctx 请求的上下文, 取消后会立即中断请求
+	ctx context.Context
 }
 
 // Request 构造自定义请求
@@ -83,6 +87,14 @@ func (r *RequestHolder) Body(body io.ReadCloser) *RequestHolder {
 	return r
 }
 
+// Context 设置请求的上下文
+//
+// 代理客户端请求时应传入客户端请求的上下文, 客户端断开连接后会立即释放上游连接
+func (r *RequestHolder) Context(ctx context.Context) *RequestHolder {
+	r.ctx = ctx
+	return r
+}
+
 // Do 发起请求 自动重定向
 func (r *RequestHolder) Do() (*http.Response, error) {
 	r.redirect = true
@@ -107,6 +119,11 @@ func (r *RequestHolder) DoRedirect() (string, *http.Response, error) {
 // 如果一个请求有多次重定向并且进行了 autoRedirect,
 // 则最后一次重定向的 url 会作为第一个参数返回
 func (r *RequestHolder) execute() (string, *http.Response, error) {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	var inner func(method, url string, header http.Header, body io.ReadCloser, autoRedirect bool, depth int) (string, *http.Response, error)
 	inner = func(method, url string, header http.Header, body io.ReadCloser, autoRedirect bool, depth int) (string, *http.Response, error) {
 		if depth >= MaxRedirectDepth {
@@ -121,7 +138,7 @@ func (r *RequestHolder) execute() (string, *http.Response, error) {
 				return "", nil, fmt.Errorf("读取请求体失败: %v", err)
 			}
 		}
-		req, err := http.NewRequest(method, url, bytes.NewBuffer(bodyBytes))
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(bodyBytes))
 		if err != nil {
 			return "", nil, fmt.Errorf("创建请求失败: %v", err)
 		}
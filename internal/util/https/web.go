@@ -73,6 +73,8 @@ func CloneHeader(dst http.ResponseWriter, src http.Header) {
 }
 
 // ProxyRequest 代理请求, 返回远程响应
+//
+// 使用客户端请求的上下文, 客户端断开连接后会中断代理请求
 func ProxyRequest(r *http.Request, remote string) (*http.Response, error) {
 	if r == nil || remote == "" {
 		return nil, errors.New("参数为空")
@@ -87,6 +89,7 @@ func ProxyRequest(r *http.Request, remote string) (*http.Response, error) {
 
 	// 2 发送请求
 	return Request(r.Method, rawUrl).
+		Context(r.Context()).
 		Header(r.Header).
 		Body(r.Body).
 		Do()
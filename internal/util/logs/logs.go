@@ -1,46 +1,84 @@
 package logs
 
 import (
+	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 )
 
+// jsonMode 是否以 json 格式输出日志
+var jsonMode atomic.Bool
+
+// SetJsonMode 设置是否以 json 格式输出日志
+func SetJsonMode(enable bool) { jsonMode.Store(enable) }
+
+// JsonMode 当前是否以 json 格式输出日志
+func JsonMode() bool { return jsonMode.Load() }
+
 // Info 输出蓝色 Info 日志
 func Info(format string, v ...any) {
-	s := fmt.Sprintf("[INFO] "+format, v...)
-	fmt.Println(now() + colors.ToBlue(s))
+	output("", "info", "[INFO] ", colors.ToBlue, format, v...)
 }
 
 // Success 输出绿色 Success 日志
 func Success(format string, v ...any) {
-	s := fmt.Sprintf("[SUCCESS] "+format, v...)
-	fmt.Println(now() + colors.ToGreen(s))
+	output("", "success", "[SUCCESS] ", colors.ToGreen, format, v...)
 }
 
 // Warn 输出黄色 Warn 日志
 func Warn(format string, v ...any) {
-	s := fmt.Sprintf("[WARN] "+format, v...)
-	fmt.Println(now() + colors.ToYellow(s))
+	output("", "warn", "[WARN] ", colors.ToYellow, format, v...)
 }
 
 // Error 输出红色 Error 日志
 func Error(format string, v ...any) {
-	s := fmt.Sprintf("[ERROR] "+format, v...)
-	fmt.Println(now() + colors.ToRed(s))
+	output("", "error", "[ERROR] ", colors.ToRed, format, v...)
 }
 
 // Tip 输出灰色 Tip 日志
 func Tip(format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	fmt.Println(now() + colors.ToGray(s))
+	output("", "tip", "", colors.ToGray, format, v...)
 }
 
 // Progress 输出紫色 Progress 日志
 func Progress(format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	fmt.Println(now() + colors.ToPurple(s))
+	output("", "progress", "", colors.ToPurple, format, v...)
+}
+
+// Entry json 格式的日志
+type Entry struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	RequestId string `json:"request_id,omitempty"`
+	Msg       string `json:"msg"`
+}
+
+// output 输出一行日志, reqId 不为空时, 会附带上请求 id
+func output(reqId, level, prefix string, color func(string) string, format string, v ...any) {
+	msg := fmt.Sprintf(format, v...)
+
+	if JsonMode() {
+		JSON(Entry{Time: time.Now().Format(time.RFC3339Nano), Level: level, RequestId: reqId, Msg: msg})
+		return
+	}
+
+	if reqId != "" {
+		prefix += "[" + reqId + "] "
+	}
+	fmt.Println(now() + color(prefix+msg))
+}
+
+// JSON 将 v 序列化为一行 json 输出
+func JSON(v any) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf(`{"time":%q,"level":"error","msg":%q}`+"\n", time.Now().Format(time.RFC3339Nano), "日志序列化失败: "+err.Error())
+		return
+	}
+	fmt.Println(string(bytes))
 }
 
 // now 返回当前时间戳
@@ -0,0 +1,67 @@
+package logs
+
+import (
+	"context"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+)
+
+// reqIdKey 请求 id 在 context 中的 key
+type reqIdKey struct{}
+
+// WithRequestId 返回携带请求 id 的 context
+//
+// 使用 Ctx(ctx) 输出的日志都会附带上请求 id,
+// 处理请求时另外启动的协程只要传递了 ctx, 同样可以附带请求 id
+func WithRequestId(ctx context.Context, reqId string) context.Context {
+	return context.WithValue(ctx, reqIdKey{}, reqId)
+}
+
+// RequestId 获取 ctx 携带的请求 id, 没有携带时返回空字符串
+func RequestId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(reqIdKey{}).(string)
+	return id
+}
+
+// Logger 输出日志时附带请求 id 的日志器
+type Logger struct {
+	reqId string
+}
+
+// Ctx 返回附带 ctx 中请求 id 的日志器
+func Ctx(ctx context.Context) Logger {
+	return Logger{reqId: RequestId(ctx)}
+}
+
+// Info 输出蓝色 Info 日志
+func (l Logger) Info(format string, v ...any) {
+	output(l.reqId, "info", "[INFO] ", colors.ToBlue, format, v...)
+}
+
+// Success 输出绿色 Success 日志
+func (l Logger) Success(format string, v ...any) {
+	output(l.reqId, "success", "[SUCCESS] ", colors.ToGreen, format, v...)
+}
+
+// Warn 输出黄色 Warn 日志
+func (l Logger) Warn(format string, v ...any) {
+	output(l.reqId, "warn", "[WARN] ", colors.ToYellow, format, v...)
+}
+
+// Error 输出红色 Error 日志
+func (l Logger) Error(format string, v ...any) {
+	output(l.reqId, "error", "[ERROR] ", colors.ToRed, format, v...)
+}
+
+// Tip 输出灰色 Tip 日志
+func (l Logger) Tip(format string, v ...any) {
+	output(l.reqId, "tip", "", colors.ToGray, format, v...)
+}
+
+// Progress 输出紫色 Progress 日志
+func (l Logger) Progress(format string, v ...any) {
+	output(l.reqId, "progress", "", colors.ToPurple, format, v...)
+}
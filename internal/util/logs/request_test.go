@@ -0,0 +1,33 @@
+package logs_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+func TestRequestId(t *testing.T) {
+	if id := logs.RequestId(context.Background()); id != "" {
+		t.Fatalf("未携带时期望返回空字符串, 实际: %s", id)
+	}
+
+	ctx := logs.WithRequestId(context.Background(), "req-a")
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 子 context 以及传递了 ctx 的其他协程都可以获取到请求 id
+	got := make(chan string)
+	go func() { got <- logs.RequestId(child) }()
+	if id := <-got; id != "req-a" {
+		t.Fatalf("请求 id 异常, 期望: req-a, 实际: %s", id)
+	}
+
+	// 覆盖后不影响原始 context
+	if id := logs.RequestId(logs.WithRequestId(ctx, "req-b")); id != "req-b" {
+		t.Fatalf("请求 id 异常, 期望: req-b, 实际: %s", id)
+	}
+	if id := logs.RequestId(ctx); id != "req-a" {
+		t.Fatalf("请求 id 异常, 期望: req-a, 实际: %s", id)
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"bufio"
+	"slices"
+	"sync"
+)
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	val    float64
+}
+
+// NewCounterVec 创建并注册一个计数器
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	cv := &CounterVec{name: name, help: help, labels: labels, series: map[string]*counterSeries{}}
+	register(name, cv)
+	return cv
+}
+
+// Inc 计数加一
+func (cv *CounterVec) Inc(values ...string) {
+	cv.Add(1, values...)
+}
+
+// Add 计数增加 v, v 不能为负数
+func (cv *CounterVec) Add(v float64, values ...string) {
+	if v < 0 {
+		return
+	}
+	checkLabels(cv.name, cv.labels, values)
+	key := seriesKey(values)
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	s, ok := cv.series[key]
+	if !ok {
+		s = &counterSeries{values: slices.Clone(values)}
+		cv.series[key] = s
+	}
+	s.val += v
+}
+
+func (cv *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, cv.name, cv.help, "counter")
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	for _, key := range sortedKeys(cv.series) {
+		s := cv.series[key]
+		writeSample(w, cv.name, cv.labels, s.values, s.val)
+	}
+}
+
+// funcCollector 在输出时才调用函数获取值的指标
+type funcCollector struct {
+	name, help, typ string
+	fn              func() float64
+}
+
+// NewGaugeFunc 创建并注册一个仪表盘, 输出时调用 fn 获取当前值
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(name, &funcCollector{name: name, help: help, typ: "gauge", fn: fn})
+}
+
+// NewCounterFunc 创建并注册一个计数器, 输出时调用 fn 获取当前值
+//
+// fn 的返回值需要保证单调递增
+func NewCounterFunc(name, help string, fn func() float64) {
+	register(name, &funcCollector{name: name, help: help, typ: "counter", fn: fn})
+}
+
+func (fc *funcCollector) write(w *bufio.Writer) {
+	writeHeader(w, fc.name, fc.help, fc.typ)
+	writeSample(w, fc.name, nil, nil, fc.fn())
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"slices"
+	"sync"
+)
+
+// DefBuckets 默认的耗时分桶, 单位: 秒
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64 // 每个分桶的计数 (非累计)
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec 创建并注册一个直方图, buckets 为空时使用 DefBuckets
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	hv := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogramSeries{}}
+	register(name, hv)
+	return hv
+}
+
+// Observe 记录一次观测值
+func (hv *HistogramVec) Observe(v float64, values ...string) {
+	checkLabels(hv.name, hv.labels, values)
+	key := seriesKey(values)
+
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	s, ok := hv.series[key]
+	if !ok {
+		s = &histogramSeries{values: slices.Clone(values), counts: make([]uint64, len(hv.buckets))}
+		hv.series[key] = s
+	}
+	if idx, _ := slices.BinarySearch(hv.buckets, v); idx < len(hv.buckets) {
+		s.counts[idx]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (hv *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, hv.name, hv.help, "histogram")
+	labels := append(slices.Clone(hv.labels), "le")
+
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	for _, key := range sortedKeys(hv.series) {
+		s := hv.series[key]
+		values := append(slices.Clone(s.values), "")
+		var cumulative uint64
+		for i, b := range hv.buckets {
+			cumulative += s.counts[i]
+			values[len(values)-1] = formatFloat(b)
+			writeSample(w, hv.name+"_bucket", labels, values, float64(cumulative))
+		}
+		values[len(values)-1] = formatFloat(math.Inf(1))
+		writeSample(w, hv.name+"_bucket", labels, values, float64(s.count))
+		writeSample(w, hv.name+"_sum", hv.labels, s.values, s.sum)
+		writeSample(w, hv.name+"_count", hv.labels, s.values, float64(s.count))
+	}
+}
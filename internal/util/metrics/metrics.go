@@ -0,0 +1,126 @@
+// 轻量级的指标统计, 以 Prometheus 文本格式输出
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType Prometheus 文本格式的响应类型
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// collector 指标收集器
+type collector interface {
+	// write 以 Prometheus 文本格式输出指标
+	write(w *bufio.Writer)
+}
+
+var (
+	// registry 已注册的所有收集器, 按照注册顺序输出
+	registry []collector
+
+	// names 已注册的指标名称, 防止重复注册
+	names = map[string]struct{}{}
+
+	registryMu sync.RWMutex
+)
+
+// register 注册收集器, 指标名称重复时 panic
+func register(name string, c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := names[name]; ok {
+		panic("metrics: 指标重复注册: " + name)
+	}
+	names[name] = struct{}{}
+	registry = append(registry, c)
+}
+
+// Write 将所有已注册的指标以 Prometheus 文本格式写入 w
+func Write(w io.Writer) error {
+	registryMu.RLock()
+	cs := slices.Clone(registry)
+	registryMu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range cs {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// writeHeader 输出指标的帮助信息以及类型
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+// writeSample 输出单条样本
+func writeSample(w *bufio.Writer, name string, labels, values []string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabel(values[i]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+// formatFloat 格式化样本值
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+// seriesKey 将标签值拼接成 map key
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// checkLabels 校验标签值个数是否与标签名个数一致
+func checkLabels(name string, labels, values []string) {
+	if len(labels) != len(values) {
+		panic(fmt.Sprintf("metrics: 指标 %s 需要 %d 个标签值, 实际传递 %d 个", name, len(labels), len(values)))
+	}
+}
+
+// sortedKeys 获取 map 排序后的 key, 保证输出顺序稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
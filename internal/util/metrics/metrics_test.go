@@ -0,0 +1,58 @@
+package metrics_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+)
+
+func TestWrite(t *testing.T) {
+	cv := metrics.NewCounterVec("test_requests_total", "请求总数", "route", "code")
+	cv.Inc("/a", "200")
+	cv.Inc("/a", "200")
+	cv.Add(3, `/b"\`, "500")
+
+	hv := metrics.NewHistogramVec("test_duration_seconds", "请求耗时", []float64{1, 0.1}, "route")
+	hv.Observe(0.05, "/a")
+	hv.Observe(0.1, "/a")
+	hv.Observe(5, "/a")
+
+	metrics.NewGaugeFunc("test_size_bytes", "缓存大小\n第二行", func() float64 { return 1024 })
+
+	var buf bytes.Buffer
+	if err := metrics.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	want := []string{
+		"# TYPE test_requests_total counter",
+		`test_requests_total{route="/a",code="200"} 2`,
+		`test_requests_total{route="/b\"\\",code="500"} 3`,
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{route="/a",le="0.1"} 2`,
+		`test_duration_seconds_bucket{route="/a",le="1"} 2`,
+		`test_duration_seconds_bucket{route="/a",le="+Inf"} 3`,
+		`test_duration_seconds_sum{route="/a"} 5.15`,
+		`test_duration_seconds_count{route="/a"} 3`,
+		`# HELP test_size_bytes 缓存大小\n第二行`,
+		"test_size_bytes 1024",
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w+"\n") {
+			t.Errorf("输出中缺少: %s\n完整输出:\n%s", w, out)
+		}
+	}
+}
+
+func TestDuplicateRegister(t *testing.T) {
+	metrics.NewCounterVec("test_dup_total", "重复注册")
+	defer func() {
+		if recover() == nil {
+			t.Error("重复注册指标时期望 panic")
+		}
+	}()
+	metrics.NewCounterVec("test_dup_total", "重复注册")
+}
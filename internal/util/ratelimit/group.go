@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// CleanInterval 清理空闲限流器的时间间隔
+const CleanInterval = time.Minute * 10
+
+// Group 按照 key 区分的一组限流器, 例如每个客户端 ip 一个限流器
+type Group struct {
+	mu sync.Mutex
+
+	// ls key => 限流器
+	ls map[string]*Limiter
+
+	// cleanedAt 最近一次清理空闲限流器的时间
+	cleanedAt time.Time
+}
+
+// Get 获取 key 对应的限流器
+//
+// 限流参数发生变化 (例如配置热重载) 时会重新创建限流器;
+// perMinute 不大于 0 时表示不限流, 返回 nil
+func (g *Group) Get(key string, perMinute, burst int) *Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	if g.ls == nil {
+		g.ls, g.cleanedAt = make(map[string]*Limiter), now
+	}
+
+	// 桶已经装满的限流器与新建的没有区别, 可以直接移除
+	if now.Sub(g.cleanedAt) > CleanInterval {
+		for k, l := range g.ls {
+			if l.idle(now) {
+				delete(g.ls, k)
+			}
+		}
+		g.cleanedAt = now
+	}
+
+	if l, ok := g.ls[key]; ok && l.perMinute == perMinute && l.burst == burst {
+		return l
+	}
+	l := NewLimiter(perMinute, burst)
+	g.ls[key] = l
+	return l
+}
+
+// Len 当前维护的限流器个数
+func (g *Group) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.ls)
+}
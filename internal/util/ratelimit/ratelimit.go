@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLimited 等待时间超过上限, 请求被限流
+var ErrLimited = errors.New("请求过于频繁, 已被限流")
+
+// Limiter 令牌桶限流器, 并发安全
+//
+// 令牌按照固定速率生成, 桶中最多存放 burst 个令牌;
+// 令牌不足时可以预支未来的令牌, 调用方需要等待到令牌生成的时间
+type Limiter struct {
+	mu sync.Mutex
+
+	// perMinute 每分钟生成的令牌数
+	perMinute int
+
+	// burst 桶的容量
+	burst int
+
+	// tokens 当前桶中的令牌数, 为负数时表示已经预支的令牌数
+	tokens float64
+
+	// last 最近一次计算令牌的时间
+	last time.Time
+}
+
+// NewLimiter 创建一个限流器, 初始时桶是满的
+func NewLimiter(perMinute, burst int) *Limiter {
+	return &Limiter{perMinute: perMinute, burst: burst, tokens: float64(burst), last: time.Now()}
+}
+
+// advance 根据流逝的时间生成令牌, 调用方需要持有锁
+func (l *Limiter) advance(now time.Time) {
+	elapsed := now.Sub(l.last)
+	if elapsed <= 0 {
+		return
+	}
+	l.last = now
+	l.tokens += elapsed.Minutes() * float64(l.perMinute)
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+}
+
+// reserve 预定一个令牌, 返回需要等待的时间
+//
+// 需要等待的时间超过 maxWait 时不会预定令牌, 返回 false
+func (l *Limiter) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(now)
+
+	var wait time.Duration
+	if lack := 1 - l.tokens; lack > 0 {
+		wait = time.Duration(lack / float64(l.perMinute) * float64(time.Minute))
+	}
+	if wait > maxWait {
+		return 0, false
+	}
+	l.tokens--
+	return wait, true
+}
+
+// restore 归还预定的令牌
+func (l *Limiter) restore() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.tokens+1, float64(l.burst))
+}
+
+// idle 判断限流器是否已经空闲, 即桶已经重新装满
+func (l *Limiter) idle(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(now)
+	return l.tokens >= float64(l.burst)
+}
+
+// Wait 从所有的限流器中各取一个令牌, 令牌不足时排队等待
+//
+// 任意一个限流器需要等待的时间超过 maxWait 时, 归还已经预定的令牌并返回 ErrLimited;
+// 为 nil 的限流器表示不限流
+func Wait(ctx context.Context, maxWait time.Duration, ls ...*Limiter) error {
+	now := time.Now()
+	var wait time.Duration
+	reserved := make([]*Limiter, 0, len(ls))
+	for _, l := range ls {
+		if l == nil {
+			continue
+		}
+		w, ok := l.reserve(now, maxWait)
+		if !ok {
+			for _, r := range reserved {
+				r.restore()
+			}
+			return ErrLimited
+		}
+		reserved = append(reserved, l)
+		wait = max(wait, w)
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		for _, r := range reserved {
+			r.restore()
+		}
+		return ctx.Err()
+	}
+}
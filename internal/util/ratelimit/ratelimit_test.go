@@ -0,0 +1,76 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/ratelimit"
+)
+
+func TestWait(t *testing.T) {
+	// 每秒生成 10 个令牌, 容量 2
+	l := ratelimit.NewLimiter(600, 2)
+	ctx := context.Background()
+
+	// 桶中的令牌可以直接使用
+	for range 2 {
+		if err := ratelimit.Wait(ctx, 0, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 令牌耗尽且不允许等待时被限流
+	if err := ratelimit.Wait(ctx, 0, l); !errors.Is(err, ratelimit.ErrLimited) {
+		t.Fatalf("期望被限流, 实际: %v", err)
+	}
+
+	// 允许等待时排队获取令牌
+	start := time.Now()
+	if err := ratelimit.Wait(ctx, time.Second, l); err != nil {
+		t.Fatal(err)
+	}
+	if cost := time.Since(start); cost < time.Millisecond*50 {
+		t.Fatalf("期望排队等待, 实际耗时: %v", cost)
+	}
+}
+
+func TestWaitMulti(t *testing.T) {
+	ctx := context.Background()
+	loose := ratelimit.NewLimiter(600, 1)
+	strict := ratelimit.NewLimiter(1, 1)
+
+	if err := ratelimit.Wait(ctx, 0, strict); err != nil {
+		t.Fatal(err)
+	}
+
+	// 严格的限流器拒绝后, 需要归还宽松限流器的令牌
+	if err := ratelimit.Wait(ctx, 0, loose, strict); !errors.Is(err, ratelimit.ErrLimited) {
+		t.Fatalf("期望被限流, 实际: %v", err)
+	}
+	if err := ratelimit.Wait(ctx, 0, loose, nil); err != nil {
+		t.Fatalf("令牌未归还: %v", err)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	var g ratelimit.Group
+	if l := g.Get("a", 0, 1); l != nil {
+		t.Fatal("不限流时期望返回 nil")
+	}
+
+	a := g.Get("a", 60, 1)
+	if g.Get("a", 60, 1) != a {
+		t.Fatal("相同 key 期望返回同一个限流器")
+	}
+	if g.Get("b", 60, 1) == a {
+		t.Fatal("不同 key 期望返回不同的限流器")
+	}
+	if g.Get("a", 120, 1) == a {
+		t.Fatal("参数变化后期望重新创建限流器")
+	}
+	if g.Len() != 2 {
+		t.Fatalf("限流器个数异常: %d", g.Len())
+	}
+}
@@ -0,0 +1,320 @@
+// 精简的 redis 客户端, 只实现了缓存共享需要用到的 RESP 协议命令
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+
+	// DefaultTimeout 未指定 context 超时时间时, 单次命令的超时时间
+	DefaultTimeout = time.Second * 3
+
+	// DefaultPoolSize 默认的空闲连接池大小
+	DefaultPoolSize = 8
+)
+
+// ErrNil key 不存在
+var ErrNil = errors.New("redis: nil")
+
+// Error redis 服务端返回的错误
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// Options 客户端配置
+type Options struct {
+	Addr     string // 服务地址 host:port
+	Password string // 访问密码, 为空时不进行认证
+	DB       int    // 数据库编号
+	PoolSize int    // 空闲连接池大小, 为 0 时使用默认值
+}
+
+// Client redis 客户端, 可以被多个 goroutine 同时使用
+type Client struct {
+	opts Options
+	pool chan *conn // 空闲连接
+}
+
+// conn 单个 redis 连接
+type conn struct {
+	nc net.Conn
+	br *bufio.Reader
+	bw *bufio.Writer
+}
+
+// NewClient 初始化客户端, 连接会在首次执行命令时建立
+func NewClient(opts Options) *Client {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = DefaultPoolSize
+	}
+	return &Client{opts: opts, pool: make(chan *conn, opts.PoolSize)}
+}
+
+// Do 执行命令, 参数支持 string, []byte 以及整数类型
+//
+// 返回值类型: 状态回复 string, 整数回复 int64, 批量回复 []byte, 多条批量回复 []any;
+// 批量回复为空时返回 ErrNil, 服务端返回错误时返回 Error
+func (c *Client) Do(ctx context.Context, args ...any) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultTimeout)
+	}
+	cn.nc.SetDeadline(deadline)
+
+	res, err := cn.do(args...)
+	var rErr Error
+	if err != nil && !errors.Is(err, ErrNil) && !errors.As(err, &rErr) {
+		// 网络或者协议异常, 连接不可复用
+		cn.nc.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return res, err
+}
+
+// Ping 检查服务是否可用
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Get 获取 key 的值, key 不存在时返回 ErrNil
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := res.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: GET 返回值类型异常: %T", res)
+	}
+	return b, nil
+}
+
+// Set 设置 key 的值, ttl 大于 0 时设置过期时间 (精确到毫秒)
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []any{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", max(ttl.Milliseconds(), 1))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// Del 删除 key, 返回实际删除的个数
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	args := make([]any, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	res, err := c.Do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.(int64)
+	return n, nil
+}
+
+// Scan 遍历所有匹配 match 的 key, fn 返回 false 时停止遍历
+//
+// 遍历过程中被修改的 key 可能会被遗漏或者重复遍历
+func (c *Client) Scan(ctx context.Context, match string, count int, fn func(key string) bool) error {
+	cursor := "0"
+	for {
+		res, err := c.Do(ctx, "SCAN", cursor, "MATCH", match, "COUNT", count)
+		if err != nil {
+			return err
+		}
+		arr, ok := res.([]any)
+		if !ok || len(arr) != 2 {
+			return fmt.Errorf("redis: SCAN 返回值格式异常")
+		}
+		next, _ := arr[0].([]byte)
+		keys, _ := arr[1].([]any)
+		for _, key := range keys {
+			if k, ok := key.([]byte); ok && !fn(string(k)) {
+				return nil
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// Close 关闭所有空闲连接
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.pool:
+			cn.nc.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// get 从连接池中获取连接, 连接池为空时新建连接
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.pool:
+		return cn, nil
+	default:
+	}
+
+	dialCtx := ctx
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	nc, err := d.DialContext(dialCtx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis: 连接失败: %w", err)
+	}
+	cn := &conn{nc: nc, br: bufio.NewReader(nc), bw: bufio.NewWriter(nc)}
+	if deadline, ok := dialCtx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+
+	if c.opts.Password != "" {
+		if _, err := cn.do("AUTH", c.opts.Password); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("redis: 认证失败: %w", err)
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := cn.do("SELECT", c.opts.DB); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("redis: 切换数据库失败: %w", err)
+		}
+	}
+	return cn, nil
+}
+
+// put 将连接放回连接池, 连接池已满时关闭连接
+func (c *Client) put(cn *conn) {
+	cn.nc.SetDeadline(time.Time{})
+	select {
+	case c.pool <- cn:
+	default:
+		cn.nc.Close()
+	}
+}
+
+// do 发送命令并读取回复
+func (cn *conn) do(args ...any) (any, error) {
+	if err := cn.write(args); err != nil {
+		return nil, err
+	}
+	return cn.read()
+}
+
+// write 以 RESP 多条批量回复的格式写入命令
+func (cn *conn) write(args []any) error {
+	fmt.Fprintf(cn.bw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		case int:
+			b = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(nil, v, 10)
+		default:
+			return fmt.Errorf("redis: 不支持的参数类型: %T", arg)
+		}
+		fmt.Fprintf(cn.bw, "$%d\r\n", len(b))
+		cn.bw.Write(b)
+		cn.bw.WriteString("\r\n")
+	}
+	return cn.bw.Flush()
+}
+
+// read 读取一个回复
+func (cn *conn) read() (any, error) {
+	line, err := cn.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("redis: 回复为空")
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("redis: 批量回复长度异常: %w", err)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.br, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("redis: 多条批量回复长度异常: %w", err)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			v, err := cn.read()
+			if err != nil && !errors.Is(err, ErrNil) {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, nil
+	default:
+		return nil, fmt.Errorf("redis: 未知的回复类型: %q", line[0])
+	}
+}
+
+// readLine 读取一行回复, 不包含结尾的 \r\n
+func (cn *conn) readLine() ([]byte, error) {
+	line, err := cn.br.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: 回复格式异常")
+	}
+	return line[:len(line)-2], nil
+}
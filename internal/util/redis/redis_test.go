@@ -0,0 +1,129 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/redis"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/redis/redistest"
+)
+
+func newServer(t *testing.T, password string) *redistest.Server {
+	s, err := redistest.NewServer(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestClient(t *testing.T) {
+	s := newServer(t, "secret")
+	c := redis.NewClient(redis.Options{Addr: s.Addr(), Password: "secret", DB: 1})
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("ping 失败: %v", err)
+	}
+
+	// 二进制数据
+	value := []byte("a\r\nb\x00c")
+	if err := c.Set(ctx, "k1", value, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Get(ctx, "k1")
+	if err != nil || string(got) != string(value) {
+		t.Fatalf("期望: %q, 实际: %q, err: %v", value, got, err)
+	}
+	if ttl := s.TTL("k1"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("过期时间异常: %v", ttl)
+	}
+
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, redis.ErrNil) {
+		t.Errorf("期望 ErrNil, 实际: %v", err)
+	}
+
+	var rErr redis.Error
+	if _, err := c.Do(ctx, "UNKNOWN"); !errors.As(err, &rErr) {
+		t.Errorf("期望服务端错误, 实际: %v", err)
+	}
+	// 服务端错误不影响连接复用
+	if err := c.Ping(ctx); err != nil {
+		t.Errorf("ping 失败: %v", err)
+	}
+
+	n, err := c.Del(ctx, "k1", "missing")
+	if err != nil || n != 1 {
+		t.Errorf("期望删除 1 个 key, 实际: %d, err: %v", n, err)
+	}
+}
+
+func TestClientAuthFailed(t *testing.T) {
+	s := newServer(t, "secret")
+	c := redis.NewClient(redis.Options{Addr: s.Addr(), Password: "wrong"})
+	defer c.Close()
+	if err := c.Ping(context.Background()); err == nil {
+		t.Error("期望认证失败")
+	}
+}
+
+func TestClientScan(t *testing.T) {
+	s := newServer(t, "")
+	c := redis.NewClient(redis.Options{Addr: s.Addr()})
+	defer c.Close()
+	ctx := context.Background()
+
+	var want []string
+	for i := range 25 {
+		key := fmt.Sprintf("p:%02d", i)
+		want = append(want, key)
+		if err := c.Set(ctx, key, []byte("v"), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Set(ctx, "other", []byte("v"), 0)
+
+	// 每次只返回少量 key, 需要根据游标多次遍历
+	var got []string
+	err := c.Scan(ctx, "p:*", 4, func(key string) bool {
+		got = append(got, key)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("期望: %v, 实际: %v", want, got)
+	}
+
+	cnt := 0
+	c.Scan(ctx, "p:*", 4, func(key string) bool {
+		cnt++
+		return cnt < 3
+	})
+	if cnt != 3 {
+		t.Errorf("提前停止遍历失败, 遍历个数: %d", cnt)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	s := newServer(t, "")
+	c := redis.NewClient(redis.Options{Addr: s.Addr()})
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务关闭后, 连接池中的连接失效
+	s.Close()
+	if err := c.Ping(ctx); err == nil {
+		t.Fatal("期望服务关闭后请求失败")
+	}
+}
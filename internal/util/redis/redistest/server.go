@@ -0,0 +1,419 @@
+// 进程内的 redis 模拟服务, 用于测试
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server 模拟的 redis 服务, 只支持字符串类型的数据
+//
+// 支持的命令: PING, AUTH, SELECT, GET, SET (EX, PX, NX), DEL, EXISTS, PTTL, STRLEN, SCAN, FLUSHDB
+type Server struct {
+	password string // 访问密码, 不为空时客户端需要先进行认证
+
+	ln      net.Listener
+	wg      sync.WaitGroup
+	connsMu sync.Mutex
+	conns   map[net.Conn]struct{}
+
+	mu   sync.Mutex
+	data map[string]item
+}
+
+// item 存储的值
+type item struct {
+	value    []byte
+	expireAt time.Time // 为零值时永不过期
+}
+
+// NewServer 启动一个监听本地随机端口的模拟服务
+//
+// password 不为空时, 客户端需要先进行认证
+func NewServer(password string) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{password: password, ln: ln, conns: make(map[net.Conn]struct{}), data: make(map[string]item)}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr 服务监听地址
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close 关闭服务, 同时断开所有连接
+func (s *Server) Close() {
+	s.ln.Close()
+	s.connsMu.Lock()
+	for nc := range s.conns {
+		nc.Close()
+	}
+	s.connsMu.Unlock()
+	s.wg.Wait()
+}
+
+// Keys 获取所有未过期的 key, 按字典序排列
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys()
+}
+
+// Get 直接读取 key 的值
+func (s *Server) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.load(key)
+	return it.value, ok
+}
+
+// TTL 获取 key 的剩余过期时间, key 不存在或者永不过期时返回 0
+func (s *Server) TTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.load(key)
+	if !ok || it.expireAt.IsZero() {
+		return 0
+	}
+	return time.Until(it.expireAt)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.connsMu.Lock()
+		s.conns[nc] = struct{}{}
+		s.connsMu.Unlock()
+		s.wg.Add(1)
+		go s.handle(nc)
+	}
+}
+
+// handle 处理单个连接上的命令
+func (s *Server) handle(nc net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connsMu.Lock()
+		delete(s.conns, nc)
+		s.connsMu.Unlock()
+		nc.Close()
+	}()
+
+	br, bw := bufio.NewReader(nc), bufio.NewWriter(nc)
+	authed := s.password == ""
+	for {
+		args, err := readCommand(br)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(string(args[0]))
+		switch {
+		case name == "AUTH":
+			if len(args) == 2 && string(args[1]) == s.password {
+				authed = true
+				bw.WriteString("+OK\r\n")
+			} else {
+				bw.WriteString("-WRONGPASS invalid password\r\n")
+			}
+		case !authed:
+			bw.WriteString("-NOAUTH Authentication required.\r\n")
+		default:
+			s.exec(bw, name, args[1:])
+		}
+		if err := bw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// exec 执行命令并写入回复
+func (s *Server) exec(bw *bufio.Writer, name string, args [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch name {
+	case "PING":
+		bw.WriteString("+PONG\r\n")
+	case "SELECT":
+		bw.WriteString("+OK\r\n")
+	case "FLUSHDB":
+		s.data = make(map[string]item)
+		bw.WriteString("+OK\r\n")
+	case "GET":
+		if len(args) != 1 {
+			writeArgsErr(bw, name)
+			return
+		}
+		it, ok := s.load(string(args[0]))
+		if !ok {
+			bw.WriteString("$-1\r\n")
+			return
+		}
+		writeBulk(bw, it.value)
+	case "SET":
+		s.set(bw, args)
+	case "DEL", "EXISTS":
+		if len(args) == 0 {
+			writeArgsErr(bw, name)
+			return
+		}
+		n := 0
+		for _, key := range args {
+			if _, ok := s.load(string(key)); ok {
+				n++
+				if name == "DEL" {
+					delete(s.data, string(key))
+				}
+			}
+		}
+		fmt.Fprintf(bw, ":%d\r\n", n)
+	case "PTTL":
+		if len(args) != 1 {
+			writeArgsErr(bw, name)
+			return
+		}
+		it, ok := s.load(string(args[0]))
+		switch {
+		case !ok:
+			bw.WriteString(":-2\r\n")
+		case it.expireAt.IsZero():
+			bw.WriteString(":-1\r\n")
+		default:
+			fmt.Fprintf(bw, ":%d\r\n", time.Until(it.expireAt).Milliseconds())
+		}
+	case "STRLEN":
+		if len(args) != 1 {
+			writeArgsErr(bw, name)
+			return
+		}
+		it, _ := s.load(string(args[0]))
+		fmt.Fprintf(bw, ":%d\r\n", len(it.value))
+	case "SCAN":
+		s.scan(bw, args)
+	default:
+		fmt.Fprintf(bw, "-ERR unknown command '%s'\r\n", name)
+	}
+}
+
+// set SET key value [EX seconds | PX milliseconds] [NX]
+func (s *Server) set(bw *bufio.Writer, args [][]byte) {
+	if len(args) < 2 {
+		writeArgsErr(bw, "SET")
+		return
+	}
+	key := string(args[0])
+	it := item{value: append([]byte(nil), args[1]...)}
+	nx := false
+	for i := 2; i < len(args); i++ {
+		opt := strings.ToUpper(string(args[i]))
+		switch opt {
+		case "NX":
+			nx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				bw.WriteString("-ERR syntax error\r\n")
+				return
+			}
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil || n <= 0 {
+				bw.WriteString("-ERR invalid expire time in 'set' command\r\n")
+				return
+			}
+			unit := time.Millisecond
+			if opt == "EX" {
+				unit = time.Second
+			}
+			it.expireAt = time.Now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			bw.WriteString("-ERR syntax error\r\n")
+			return
+		}
+	}
+	if _, ok := s.load(key); ok && nx {
+		bw.WriteString("$-1\r\n")
+		return
+	}
+	s.data[key] = it
+	bw.WriteString("+OK\r\n")
+}
+
+// scan SCAN cursor [MATCH pattern] [COUNT count]
+//
+// 游标为按字典序排列的 key 列表中的偏移量
+func (s *Server) scan(bw *bufio.Writer, args [][]byte) {
+	if len(args) < 1 {
+		writeArgsErr(bw, "SCAN")
+		return
+	}
+	cursor, err := strconv.Atoi(string(args[0]))
+	if err != nil || cursor < 0 {
+		bw.WriteString("-ERR invalid cursor\r\n")
+		return
+	}
+	var match *regexp.Regexp
+	count := 10
+	for i := 1; i+1 < len(args); i += 2 {
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			match = globRegexp(string(args[i+1]))
+		case "COUNT":
+			if n, err := strconv.Atoi(string(args[i+1])); err == nil && n > 0 {
+				count = n
+			}
+		}
+	}
+
+	keys := s.keys()
+	end := min(cursor+count, len(keys))
+	var res []string
+	if cursor < len(keys) {
+		for _, key := range keys[cursor:end] {
+			if match == nil || match.MatchString(key) {
+				res = append(res, key)
+			}
+		}
+	}
+	next := end
+	if next >= len(keys) {
+		next = 0
+	}
+
+	bw.WriteString("*2\r\n")
+	writeBulk(bw, []byte(strconv.Itoa(next)))
+	fmt.Fprintf(bw, "*%d\r\n", len(res))
+	for _, key := range res {
+		writeBulk(bw, []byte(key))
+	}
+}
+
+// load 获取未过期的值, 已过期的值会被删除
+//
+// 需要在持有锁的情况下调用
+func (s *Server) load(key string) (item, bool) {
+	it, ok := s.data[key]
+	if !ok {
+		return item{}, false
+	}
+	if !it.expireAt.IsZero() && time.Now().After(it.expireAt) {
+		delete(s.data, key)
+		return item{}, false
+	}
+	return it, true
+}
+
+// keys 获取所有未过期的 key, 按字典序排列
+//
+// 需要在持有锁的情况下调用
+func (s *Server) keys() []string {
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		if _, ok := s.load(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// readCommand 读取一条 RESP 格式的命令
+func readCommand(br *bufio.Reader) ([][]byte, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("不支持的命令格式: %q", line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("命令长度异常: %q", line)
+	}
+
+	args := make([][]byte, n)
+	for i := range args {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("参数格式异常: %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("参数长度异常: %q", line)
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, err
+		}
+		args[i] = b[:size]
+	}
+	return args, nil
+}
+
+// globRegexp 将 redis 的 glob 表达式转换为正则表达式
+func globRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			sb.WriteString("(?s:.*)")
+		case '?':
+			sb.WriteString("(?s:.)")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "^") {
+				class = "^" + regexp.QuoteMeta(class[1:])
+			} else {
+				class = regexp.QuoteMeta(class)
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+func writeBulk(bw *bufio.Writer, b []byte) {
+	fmt.Fprintf(bw, "$%d\r\n", len(b))
+	bw.Write(b)
+	bw.WriteString("\r\n")
+}
+
+func writeArgsErr(bw *bufio.Writer, name string) {
+	fmt.Fprintf(bw, "-ERR wrong number of arguments for '%s' command\r\n", strings.ToLower(name))
+}
@@ -4,7 +4,9 @@ import (
 	"log"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 )
@@ -90,3 +92,66 @@ func Unescape(rawUrl string) string {
 
 	return dc
 }
+
+// expiryParams 直链中表示过期时间的参数, 参数名 => 签名时间参数名
+//
+// 签名时间参数名不为空时, 过期参数的值可以是相对于签名时间的秒数
+var expiryParams = []struct{ name, dateName string }{
+	{name: "X-Amz-Expires", dateName: "X-Amz-Date"},
+	{name: "x-oss-expires", dateName: "x-oss-date"},
+	{name: "Expires"},
+	{name: "t"},
+}
+
+// signDateLayout 签名时间参数的格式, 如: 20240101T120000Z
+const signDateLayout = "20060102T150405Z"
+
+// LinkExpiry 从直链的参数中解析链接的过期时间
+//
+// 支持 Expires, x-oss-expires, X-Amz-Expires, t 等参数, 参数名不区分大小写,
+// 参数值可以是秒级或毫秒级的时间戳, 也可以是相对于签名时间的秒数
+//
+// 无法解析时返回 false; 链接已经过期时, 同样返回解析到的过期时间, 由调用方判断
+func LinkExpiry(rawUrl string, now time.Time) (time.Time, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return time.Time{}, false
+	}
+	q := u.Query()
+
+	// get 不区分大小写获取参数值
+	get := func(name string) string {
+		for key, values := range q {
+			if strings.EqualFold(key, name) && len(values) > 0 {
+				return values[0]
+			}
+		}
+		return ""
+	}
+
+	for _, p := range expiryParams {
+		num, err := strconv.ParseInt(get(p.name), 10, 64)
+		if err != nil || num <= 0 {
+			continue
+		}
+
+		var expiry time.Time
+		switch {
+		case num >= 1e12:
+			expiry = time.UnixMilli(num)
+		case num >= 1e9:
+			expiry = time.Unix(num, 0)
+		case p.dateName != "":
+			signDate, err := time.Parse(signDateLayout, get(p.dateName))
+			if err != nil {
+				signDate = now
+			}
+			expiry = signDate.Add(time.Duration(num) * time.Second)
+		default:
+			continue
+		}
+
+		return expiry, true
+	}
+	return time.Time{}, false
+}
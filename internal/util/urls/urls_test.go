@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
 )
@@ -64,3 +65,30 @@ func TestUnescape(t *testing.T) {
 		})
 	}
 }
+
+func TestLinkExpiry(t *testing.T) {
+	now := time.Date(2024, 9, 19, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		link string
+		want time.Time
+		ok   bool
+	}{
+		{name: "oss 时间戳", link: "https://a.oss.aliyuncs.com/v.mp4?x-oss-expires=1726738969&x-oss-signature=x", want: time.Unix(1726738969, 0), ok: true},
+		{name: "oss v4 相对时间", link: "https://a.oss.aliyuncs.com/v.mp4?x-oss-date=20240919T085800Z&x-oss-expires=300", want: time.Date(2024, 9, 19, 9, 3, 0, 0, time.UTC), ok: true},
+		{name: "s3 相对时间", link: "https://s3.amazonaws.com/b/v.mp4?X-Amz-Date=20240919T080000Z&X-Amz-Expires=14400&X-Amz-Signature=x", want: time.Date(2024, 9, 19, 12, 0, 0, 0, time.UTC), ok: true},
+		{name: "参数名大小写", link: "https://cdn.example.com/v.mp4?expires=1726740000", want: time.Unix(1726740000, 0), ok: true},
+		{name: "毫秒时间戳", link: "https://cdn.example.com/v.mp4?t=1726740000000", want: time.UnixMilli(1726740000000), ok: true},
+		{name: "已过期", link: "https://cdn.example.com/v.mp4?Expires=1726730000", want: time.Unix(1726730000, 0), ok: true},
+		{name: "无过期参数", link: "https://cdn.example.com/v.mp4?sign=abc"},
+		{name: "t 不是时间戳", link: "https://cdn.example.com/v.mp4?t=5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := urls.LinkExpiry(tt.link, now)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("LinkExpiry() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
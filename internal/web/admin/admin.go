@@ -0,0 +1,144 @@
+// 管理接口, 用于在运行时查看和维护程序的内存状态
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/m3u8"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist/localtree"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// endpoints 管理接口的处理器, key 为 "请求方法 子路径"
+var endpoints = map[string]func(*gin.Context){
+	"GET cache":           cacheStats,
+	"POST cache/purge":    cachePurge,
+	"GET playlists":       playlists,
+	"GET localtree":       localtreeStatus,
+	"POST localtree/sync": localtreeSync,
+	"GET apikeys":         apiKeys,
+	"DELETE apikeys":      revokeApiKey,
+}
+
+// Handle 管理接口统一入口
+//
+// 需要在请求头中携带 Authorization: Bearer <admin.token>
+func Handle(c *gin.Context) {
+	cfg := config.C().Admin
+	if !cfg.Enable {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if !authorized(c, cfg.Token) {
+		logs.Warn("管理接口鉴权失败, ip: %s, uri: %s", c.ClientIP(), c.Request.URL.Path)
+		c.JSON(http.StatusUnauthorized, gin.H{"msg": "鉴权失败"})
+		return
+	}
+
+	var sub string
+	if matches := c.GetStringSlice(constant.RouteSubMatchGinKey); len(matches) > 1 {
+		sub = strings.Trim(strings.ToLower(matches[1]), "/")
+	}
+
+	handler, ok := endpoints[c.Request.Method+" "+sub]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"msg": "接口不存在"})
+		return
+	}
+	handler(c)
+}
+
+// authorized 校验请求中的管理令牌
+func authorized(c *gin.Context, token string) bool {
+	auth := c.GetHeader("Authorization")
+	reqToken, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok || reqToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) == 1
+}
+
+// cacheStats 缓存统计信息
+func cacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, cache.GetStats())
+}
+
+// cachePurge 清理缓存
+//
+// 支持的 query 参数 (任选其一):
+// key: 缓存 key;
+// space: 缓存空间名称, 可同时传递 space_key 清理空间中的单个缓存;
+// regex: 匹配请求 uri 的正则表达式
+func cachePurge(c *gin.Context) {
+	var purged int
+	switch {
+	case c.Query("key") != "":
+		purged = cache.PurgeKey(c.Query("key"))
+	case c.Query("space") != "":
+		purged = cache.PurgeSpace(c.Query("space"), c.Query("space_key"))
+	case c.Query("regex") != "":
+		reg, err := regexp.Compile(c.Query("regex"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"msg": "正则表达式编译失败: " + err.Error()})
+			return
+		}
+		purged = cache.PurgeRegex(reg)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"msg": "缺少参数: key, space 或 regex"})
+		return
+	}
+	logs.Info("管理接口清理缓存 %d 条, 参数: %s", purged, c.Request.URL.RawQuery)
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+// playlists 内存中正在维护的 m3u8 播放列表
+func playlists(c *gin.Context) {
+	if m3u8.ListPlaylists == nil {
+		c.JSON(http.StatusOK, []m3u8.Summary{})
+		return
+	}
+	c.JSON(http.StatusOK, m3u8.ListPlaylists())
+}
+
+// localtreeStatus 本地目录树同步状态
+func localtreeStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, localtree.GetStatus())
+}
+
+// localtreeSync 触发本地目录树立即同步
+func localtreeSync(c *gin.Context) {
+	if err := localtree.TriggerSync(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"msg": "已触发同步"})
+}
+
+// apiKeys 已经校验通过的 api_key 列表
+func apiKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, emby.ListApiKeys())
+}
+
+// revokeApiKey 吊销 api_key
+//
+// query 参数: key 需要吊销的 api_key; upstream 所属的 emby 上游名称, 为空时吊销所有上游
+func revokeApiKey(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": "缺少参数: key"})
+		return
+	}
+	revoked := emby.RevokeApiKey(c.Query("upstream"), key)
+	logs.Info("管理接口吊销 api_key %d 个", revoked)
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
@@ -0,0 +1,104 @@
+package admin_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/admin"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testToken = "0123456789abcdef"
+
+func initConfig(t *testing.T, enable bool) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := fmt.Sprintf(`emby:
+  host: http://127.0.0.1:8096
+openlist:
+  host: http://127.0.0.1:5244
+  token: stub-token
+admin:
+  enable: %v
+  token: %s
+`, enable, testToken)
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newEngine 模拟全局路由处理器, 将正则匹配结果写入上下文后交给管理接口处理
+func newEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	reg := regexp.MustCompile(constant.Reg_Admin)
+	r := gin.New()
+	r.Any("/*vars", func(c *gin.Context) {
+		c.Set(constant.RouteSubMatchGinKey, reg.FindStringSubmatch(c.Request.RequestURI))
+		admin.Handle(c)
+	})
+	return r
+}
+
+func do(r *gin.Engine, method, uri, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, uri, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandle(t *testing.T) {
+	initConfig(t, true)
+	r := newEngine()
+
+	tests := []struct {
+		name   string
+		method string
+		uri    string
+		token  string
+		want   int
+	}{
+		{"缺少令牌", http.MethodGet, "/ge2o/admin/cache", "", http.StatusUnauthorized},
+		{"错误令牌", http.MethodGet, "/ge2o/admin/cache", "wrong-token-value", http.StatusUnauthorized},
+		{"缓存统计", http.MethodGet, "/ge2o/admin/cache", testToken, http.StatusOK},
+		{"清理缓存缺少参数", http.MethodPost, "/ge2o/admin/cache/purge", testToken, http.StatusBadRequest},
+		{"清理缓存错误正则", http.MethodPost, "/ge2o/admin/cache/purge?regex=(", testToken, http.StatusBadRequest},
+		{"按正则清理缓存", http.MethodPost, "/ge2o/admin/cache/purge?regex=.*", testToken, http.StatusOK},
+		{"目录树状态", http.MethodGet, "/ge2o/admin/localtree", testToken, http.StatusOK},
+		{"目录树未启用", http.MethodPost, "/ge2o/admin/localtree/sync", testToken, http.StatusConflict},
+		{"api_key 列表", http.MethodGet, "/ge2o/admin/apikeys", testToken, http.StatusOK},
+		{"吊销缺少参数", http.MethodDelete, "/ge2o/admin/apikeys", testToken, http.StatusBadRequest},
+		{"吊销 api_key", http.MethodDelete, "/ge2o/admin/apikeys?key=abc", testToken, http.StatusOK},
+		{"请求方法不匹配", http.MethodDelete, "/ge2o/admin/cache", testToken, http.StatusNotFound},
+		{"接口不存在", http.MethodGet, "/ge2o/admin/unknown", testToken, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(r, tt.method, tt.uri, tt.token)
+			if w.Code != tt.want {
+				t.Errorf("期望状态码: %d, 实际: %d, 响应: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleDisabled(t *testing.T) {
+	initConfig(t, false)
+	w := do(newEngine(), http.MethodGet, "/ge2o/admin/cache", testToken)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("管理接口关闭时期望状态码 404, 实际: %d", w.Code)
+	}
+}
@@ -0,0 +1,143 @@
+// 缓存存储后端, 默认使用进程内存存储缓存,
+// 多实例部署时可以使用 redis 在实例之间共享缓存以及缓存空间
+package cache
+
+import (
+	"sync"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// backend 缓存存储后端
+type backend interface {
+	// name 后端名称
+	name() string
+
+	// get 根据 cacheKey 获取缓存
+	get(cacheKey string) (*respCache, bool)
+
+	// getSpace 获取缓存空间中的缓存
+	getSpace(space, spaceKey string) (*respCache, bool)
+
+	// put 写入缓存, 已存在相同 cacheKey 的缓存时进行覆盖
+	put(rc *respCache)
+
+	// update 缓存对象被修改后, 重新写入
+	update(rc *respCache)
+
+	// remove 移除缓存, 同时移除缓存空间中的引用, 返回是否移除成功
+	remove(rc *respCache) bool
+
+	// rangeAll 遍历所有缓存, fn 返回 false 时停止遍历
+	rangeAll(fn func(rc *respCache) bool)
+
+	// rangeSpace 遍历缓存空间中的缓存, fn 返回 false 时停止遍历
+	rangeSpace(space string, fn func(spaceKey string, rc *respCache) bool)
+
+	// usage 获取缓存条目数以及总大小
+	usage() (int, int64)
+
+	// spaces 获取各缓存空间的条目数
+	spaces() map[string]int
+
+	// close 释放后端占用的资源
+	close()
+}
+
+// storage 当前使用的缓存存储后端
+//
+// 在服务启动前通过 InitBackend 初始化, 运行期间不会变更
+var storage backend = memoryBackend{}
+
+// InitBackend 根据 cache.backend 配置初始化缓存存储后端
+func InitBackend() error {
+	if config.C().Cache.Backend != config.CacheBackendRedis {
+		storage = memoryBackend{}
+		return nil
+	}
+
+	rb, err := newRedisBackend(config.C().Cache.Redis)
+	if err != nil {
+		return err
+	}
+	storage = rb
+	logs.Success("缓存存储后端: redis, 地址: %s", config.C().Cache.Redis.Addr)
+	return nil
+}
+
+// memoryBackend 进程内存存储, 超出容量时淘汰最近最少使用的缓存, 可同步写入磁盘
+type memoryBackend struct{}
+
+func (memoryBackend) name() string {
+	return config.CacheBackendMemory
+}
+
+func (memoryBackend) get(cacheKey string) (*respCache, bool) {
+	return store.get(cacheKey)
+}
+
+func (memoryBackend) getSpace(space, spaceKey string) (*respCache, bool) {
+	return getSpaceCache(getSpace(space), spaceKey)
+}
+
+func (memoryBackend) put(rc *respCache) {
+	if _, ok := storeCache(rc); ok {
+		persistCache(rc)
+	}
+}
+
+func (memoryBackend) update(rc *respCache) {
+	rc.mu.RLock()
+	size := int64(len(rc.body))
+	rc.mu.RUnlock()
+	resizeCache(rc, size)
+	persistCache(rc)
+}
+
+// remove 缓存已经被其他请求覆盖时, 不做处理
+func (memoryBackend) remove(rc *respCache) bool {
+	if !store.remove(rc) {
+		return false
+	}
+	unpersistCache(rc.cacheKey)
+	return true
+}
+
+func (memoryBackend) rangeAll(fn func(rc *respCache) bool) {
+	for _, rc := range store.snapshot() {
+		if !fn(rc) {
+			return
+		}
+	}
+}
+
+func (memoryBackend) rangeSpace(space string, fn func(spaceKey string, rc *respCache) bool) {
+	s, ok := spaceMap.Load(space)
+	if !ok {
+		return
+	}
+	s.(*sync.Map).Range(func(key, value any) bool {
+		return fn(key.(string), value.(*respCache))
+	})
+}
+
+func (memoryBackend) usage() (int, int64) {
+	return store.usage()
+}
+
+func (memoryBackend) spaces() map[string]int {
+	res := make(map[string]int)
+	spaceMap.Range(func(key, value any) bool {
+		cnt := 0
+		value.(*sync.Map).Range(func(key, value any) bool {
+			cnt++
+			return true
+		})
+		res[key.(string)] = cnt
+		return true
+	})
+	return res
+}
+
+func (memoryBackend) close() {}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/encrypts"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
@@ -42,10 +44,15 @@ var CacheKeyIgnoreParams = map[string]struct{}{
 	"True-Client-IP": {}, "CF-Connecting-IP": {}, "X-Cluster-Client-IP": {},
 	"Fastly-Client-IP": {}, "X-Client-IP": {}, "X-ProxyUser-IP": {},
 	"Via": {}, "Forwarded-For": {}, "X-From-Cdn": {},
+
+	// 服务内部自请求, 上游名称已经单独参与 cacheKey 运算
+	constant.HeaderEmbyUpstream: {}, constant.HeaderInternalToken: {},
 }
 
 // CacheableRouteMarker 缓存白名单
 // 只有匹配上正则表达式的路由才会被缓存
+//
+// cache.routes 中的自定义规则优先于内置规则匹配
 func CacheableRouteMarker() gin.HandlerFunc {
 	cacheablePatterns := []*regexp.Regexp{
 		regexp.MustCompile(constant.Reg_PlaybackInfo),
@@ -57,6 +64,17 @@ func CacheableRouteMarker() gin.HandlerFunc {
 	}
 
 	return func(c *gin.Context) {
+		// 缓存开关支持热重载, 需要在请求时判断
+		if !config.C().Cache.Enable {
+			c.Header(HeaderKeyExpired, "-1")
+			return
+		}
+
+		if route, ok := config.C().Cache.MatchRoute(c.Request.RequestURI); ok {
+			c.Set(constant.CacheRouteGinKey, route)
+			return
+		}
+
 		for _, pattern := range cacheablePatterns {
 			if pattern.MatchString(c.Request.RequestURI) {
 				return
@@ -74,54 +92,149 @@ func RequestCacher() gin.HandlerFunc {
 			return
 		}
 
-		// 2 计算 cache key
+		// 2 计算 cache key, 后台刷新缓存的请求需要跳过缓存直接请求
+		revalidating := isRevalidateRequest(c.Request)
 		cacheKey, err := calcCacheKey(c)
 		if err != nil {
-			logs.Warn("cache key 计算异常: %v, 跳过缓存", err)
+			logs.Ctx(c.Request.Context()).Warn("cache key 计算异常: %v, 跳过缓存", err)
 			// 如果没有调用 Abort, Gin 会自动继续调用处理器链
 			return
 		}
 
 		// 3 尝试获取缓存
-		if rc, ok := getCache(cacheKey); ok {
-			if https.IsRedirectCode(rc.code) {
-				// 适配重定向请求
-				c.Redirect(rc.code, rc.header.header.Get("Location"))
-			} else {
-				c.Status(rc.code)
-				https.CloneHeader(c.Writer, rc.header.header)
-				c.Writer.Write(rc.body)
+		if rc, ok := storage.get(cacheKey); ok && !revalidating {
+			nowMillis := time.Now().UnixMilli()
+			switch {
+			case nowMillis <= rc.expired:
+				hits.Add(1)
+				replayCache(c, rc)
+				return
+			case nowMillis <= rc.stale:
+				// 缓存已过期, 先使用旧的缓存响应, 再在后台刷新
+				stales.Add(1)
+				replayCache(c, rc)
+				revalidate(rc, c.Request)
+				return
+			default:
+				storage.remove(rc)
+			}
+		}
+
+		// 4 相同的请求正在处理中时, 等待其响应后直接复用
+		//
+		// 后台刷新的请求不参与合并, 避免客户端等待刷新结果
+		var cl *call
+		leader := false
+		if !revalidating {
+			cl, leader = joinCall(cacheKey)
+		}
+		if cl != nil && !leader {
+			if rc, ok := cl.wait(c.Request.Context()); ok {
+				coalesced.Add(1)
+				replayCache(c, rc)
+				return
+			}
+			if c.Request.Context().Err() != nil {
+				c.Abort()
+				return
 			}
-			c.Abort()
+			// 等待超时或响应不可缓存, 自行请求上游
+		}
+
+		// leader 退出时 (包括异常提前返回) 必须唤醒等待中的请求, 否则 calls 会泄漏
+		var rc *respCache
+		if leader {
+			defer func() { cl.finish(cacheKey, rc) }()
+		}
+
+		if !revalidating {
+			misses.Add(1)
+		}
+		req, err := snapshotRequest(c)
+		if err != nil {
+			logs.Ctx(c.Request.Context()).Warn("记录原始请求信息异常: %v, 跳过缓存", err)
 			return
 		}
 
-		// 4 使用自定义的响应器
+		// 5 使用自定义的响应器
 		customWriter := &respCacheWriter{body: &bytes.Buffer{}, ResponseWriter: c.Writer}
+		if leader {
+			// 响应不可缓存时 (如回源代理的媒体流), 无需等到请求结束, 立即唤醒等待中的请求
+			customWriter.onWrite = func(header http.Header) {
+				if header.Get(HeaderKeyExpired) == "-1" {
+					cl.finish(cacheKey, nil)
+				}
+			}
+		}
 		c.Writer = customWriter
 
-		// 5 执行请求处理器
+		// 6 执行请求处理器
 		c.Next()
 
-		// 6 不缓存错误请求
+		// 7 不缓存错误请求
 		if https.IsErrorStatus(c.Writer.Status()) {
 			return
 		}
 
-		// 7 刷新缓存
+		// 8 刷新缓存
 		header := c.Writer.Header()
 		respHeader := respHeader{
 			expired:  header.Get(HeaderKeyExpired),
+			stale:    header.Get(HeaderKeyStale),
 			space:    header.Get(HeaderKeySpace),
 			spaceKey: header.Get(HeaderKeySpaceKey),
 			header:   header.Clone(),
 		}
+		// 请求 id 只属于当前请求, 不能随缓存复用
+		respHeader.header.Del(constant.HeaderRequestId)
 		defer header.Del(HeaderKeyExpired)
+		defer header.Del(HeaderKeyStale)
 		defer header.Del(HeaderKeySpace)
 		defer header.Del(HeaderKeySpaceKey)
 
-		go putCache(cacheKey, c, append([]byte(nil), customWriter.body.Bytes()...), respHeader)
+		newRc, ok := newRespCache(cacheKey, c, append([]byte(nil), customWriter.body.Bytes()...), respHeader, req)
+		if !ok {
+			return
+		}
+		rc = newRc
+		cacheHandleWaitGroup.Add(1)
+		go putCache(rc)
+	}
+}
+
+// snapshotRequest 记录原始请求的信息, 用于后台刷新缓存时重放请求
+func snapshotRequest(c *gin.Context) (replayRequest, error) {
+	req := replayRequest{method: c.Request.Method, header: c.Request.Header.Clone()}
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return req, fmt.Errorf("读取请求体失败: %v", err)
+		}
+		req.body = body
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+	}
+
+	// 重放请求时需要保持与原始请求一致的上游, 自请求凭证在重放时注入
+	if e, ok := c.Get(constant.EmbyUpstreamGinKey); ok {
+		req.header.Set(constant.HeaderEmbyUpstream, e.(*config.Emby).Name)
+	}
+	req.header.Del(constant.HeaderInternalToken)
+	req.header.Del(constant.HeaderRequestId)
+	return req, nil
+}
+
+// replayCache 使用缓存响应客户端
+func replayCache(c *gin.Context, rc *respCache) {
+	c.Set(constant.CacheHitGinKey, true)
+	if code := rc.Code(); https.IsRedirectCode(code) {
+		// 适配重定向请求
+		c.Redirect(code, rc.Header("Location"))
+	} else {
+		c.Status(code)
+		https.CloneHeader(c.Writer, rc.Headers())
+		rc.writeBody(c.Writer, c.Request)
 	}
+	c.Abort()
 }
 
 // Duration 将一个标准的时间转换成适用于缓存时间的字符串
@@ -130,7 +243,7 @@ func Duration(d time.Duration) string {
 	return fmt.Sprintf("%v", expired)
 }
 
-// WaitingForHandleChan 等待预缓存通道被处理完毕
+// WaitingForHandleChan 等待异步写入的缓存处理完毕
 func WaitingForHandleChan() {
 	cacheHandleWaitGroup.Wait()
 }
@@ -139,8 +252,11 @@ func WaitingForHandleChan() {
 //
 // 计算方式: 取出 请求方法, 请求路径, 请求体, 请求头 转换成字符串之后字典排序,
 // 再进行 Md5Hash
+//
+// 请求匹配自定义缓存规则时, 按照规则过滤参与计算的请求参数和请求头
 func calcCacheKey(c *gin.Context) (string, error) {
 	method := c.Request.Method
+	route, _ := cacheRoute(c)
 
 	q := c.Request.URL.Query()
 	for key := range CacheKeyIgnoreParams {
@@ -149,6 +265,17 @@ func calcCacheKey(c *gin.Context) (string, error) {
 	c.Request.URL.RawQuery = q.Encode()
 	uri := c.Request.URL.String()
 
+	// 自定义规则只影响 cache key, 不修改原始请求
+	keyQuery := c.Request.URL.RawQuery
+	if route != nil {
+		for key := range q {
+			if !route.Params.Keep(key) {
+				q.Del(key)
+			}
+		}
+		keyQuery = q.Encode()
+	}
+
 	body := ""
 	if c.Request.Body != nil {
 		bodyBytes, err := io.ReadAll(c.Request.Body)
@@ -163,6 +290,9 @@ func calcCacheKey(c *gin.Context) (string, error) {
 		if _, ok := CacheKeyIgnoreParams[key]; ok {
 			continue
 		}
+		if route != nil && !route.Headers.Keep(key) {
+			continue
+		}
 		header.WriteString(key)
 		header.WriteString("=")
 		header.WriteString(strings.Join(values, "|"))
@@ -170,9 +300,9 @@ func calcCacheKey(c *gin.Context) (string, error) {
 	}
 
 	headerStr := header.String()
-	preEnc := strs.Sort(c.Request.URL.RawQuery + body + headerStr)
+	preEnc := strs.Sort(keyQuery + body + headerStr)
 	if headerStr != "" {
-		logs.Tip("headers to encode cacheKey: %s", colors.ToYellow(headerStr))
+		logs.Ctx(c.Request.Context()).Tip("headers to encode cacheKey: %s", colors.ToYellow(headerStr))
 	}
 
 	// 为防止字典排序后, 不同的 uri 冲突, 这里在排序完的字符串前再加上原始的 uri
@@ -182,6 +312,59 @@ func calcCacheKey(c *gin.Context) (string, error) {
 		c.Request.URL.RawQuery, "",
 	)
 
-	hash := encrypts.Md5Hash(method + uriNoArgs + preEnc)
+	// 不同 emby 上游的相同请求需要区分缓存
+	upstream := config.DefaultUpstreamName
+	if e, ok := c.Get(constant.EmbyUpstreamGinKey); ok {
+		upstream = e.(*config.Emby).Name
+	}
+
+	// 按照 emby 用户区分缓存
+	if route != nil && route.PerUser {
+		upstream += "_" + embyUser(c)
+	}
+
+	hash := encrypts.Md5Hash(upstream + method + uriNoArgs + preEnc)
 	return hash, nil
 }
+
+// cacheRoute 获取当前请求匹配到的自定义缓存规则
+func cacheRoute(c *gin.Context) (*config.CacheRoute, bool) {
+	if v, ok := c.Get(constant.CacheRouteGinKey); ok {
+		return v.(*config.CacheRoute), true
+	}
+	return nil, false
+}
+
+// userPathReg 从请求路径中解析 emby 用户 id
+var userPathReg = regexp.MustCompile(`(?i)/Users/([^/?]+)`)
+
+// tokenReg 从授权请求头中解析访问令牌
+var tokenReg = regexp.MustCompile(`(?i)Token="([^"]+)"`)
+
+// embyUser 获取请求所属的 emby 用户标识
+//
+// 优先使用用户 id, 请求中不包含用户 id 时, 使用客户端的访问令牌
+func embyUser(c *gin.Context) string {
+	q := c.Request.URL.Query()
+	if id := q.Get("UserId"); id != "" {
+		return id
+	}
+	if m := userPathReg.FindStringSubmatch(c.Request.URL.Path); m != nil {
+		return m[1]
+	}
+
+	for _, key := range []string{"api_key", "X-Emby-Token"} {
+		if token := q.Get(key); token != "" {
+			return token
+		}
+	}
+	if token := c.GetHeader("X-Emby-Token"); token != "" {
+		return token
+	}
+	for _, key := range []string{"X-Emby-Authorization", "Authorization"} {
+		if m := tokenReg.FindStringSubmatch(c.GetHeader(key)); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package cache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCacheKey(t *testing.T) {
+	initConfig(t, `  routes:
+    - pattern: (?i)^/key/user
+      per-user: true
+      params:
+        include: [id]
+      headers:
+        include: [X-None]
+    - pattern: (?i)^/key/filter
+      params:
+        exclude: [nonce]
+      headers:
+        exclude: [X-Client]
+    - pattern: (?i)^/key/ttl
+      expired: 1h
+`)
+	f := newFixture("")
+	f.handle("/key/*any", func(c *gin.Context) {
+		if ttl, err := time.ParseDuration(c.Query("ttl")); err == nil {
+			c.Header(cache.HeaderKeyExpired, cache.Duration(ttl))
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	// hits 依次发起请求, 返回处理器被调用的次数
+	//
+	// 请求路径统一拼接 id 参数, 避免复用之前用例的缓存
+	id := strconv.FormatInt(time.Now().UnixNano(), 10)
+	hits := func(reqs ...*http.Request) int32 {
+		before := f.calls.Load()
+		for _, req := range reqs {
+			q := req.URL.Query()
+			q.Set("id", id)
+			req.URL.RawQuery = q.Encode()
+			req.RequestURI = req.URL.RequestURI()
+			f.do(req)
+			cache.WaitingForHandleChan()
+		}
+		return f.calls.Load() - before
+	}
+	// newReq 初始化请求, header 为 key, value 交替的请求头
+	newReq := func(uri string, header ...string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, uri, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		return req
+	}
+
+	t.Run("per user", func(t *testing.T) {
+		// 用户标识不参与参数和请求头的计算, 只通过 per-user 区分缓存
+		if n := hits(
+			newReq("/key/user/items?UserId=u1"),
+			newReq("/key/user/items?UserId=u2"),
+			newReq("/key/user/items?UserId=u1"),
+		); n != 2 {
+			t.Errorf("按用户 id 区分缓存, 期望调用 2 次, 实际: %d", n)
+		}
+		if n := hits(
+			newReq("/key/user/Users/u3/Items"),
+			newReq("/key/user/Users/u4/Items"),
+			newReq("/key/user/Users/u3/Items"),
+		); n != 2 {
+			t.Errorf("按路径中的用户 id 区分缓存, 期望调用 2 次, 实际: %d", n)
+		}
+		if n := hits(
+			newReq("/key/user/token?api_key=t1"),
+			newReq("/key/user/token", "X-Emby-Token", "t2"),
+			newReq("/key/user/token", "X-Emby-Authorization", `MediaBrowser Client="a", Token="t3"`),
+			newReq("/key/user/token", "X-Emby-Authorization", `MediaBrowser Client="a", Token="t3"`),
+		); n != 3 {
+			t.Errorf("按访问令牌区分缓存, 期望调用 3 次, 实际: %d", n)
+		}
+	})
+
+	t.Run("excluded params", func(t *testing.T) {
+		if n := hits(
+			newReq("/key/filter?nonce=1", "X-Client", "a"),
+			newReq("/key/filter?nonce=2", "X-Client", "b"),
+			newReq("/key/filter?nonce=3"),
+		); n != 1 {
+			t.Errorf("排除的参数和请求头不应影响 cache key, 期望调用 1 次, 实际: %d", n)
+		}
+		// 内置忽略的参数同样不参与计算
+		if n := hits(
+			newReq("/key/filter/session?PlaySessionId=s1", "X-Forwarded-For", "1.1.1.1"),
+			newReq("/key/filter/session?PlaySessionId=s2", "X-Forwarded-For", "2.2.2.2"),
+		); n != 1 {
+			t.Errorf("内置忽略的参数不应影响 cache key, 期望调用 1 次, 实际: %d", n)
+		}
+		if n := hits(
+			newReq("/key/filter/other?keep=1"),
+			newReq("/key/filter/other?keep=2"),
+		); n != 2 {
+			t.Errorf("未排除的参数需要参与计算, 期望调用 2 次, 实际: %d", n)
+		}
+	})
+
+	t.Run("route ttl capped by handler", func(t *testing.T) {
+		req := func() *http.Request { return newReq("/key/ttl?ttl=200ms") }
+		if n := hits(req(), req()); n != 1 {
+			t.Fatalf("期望命中缓存, 处理器调用次数: %d", n)
+		}
+		// 接口声明的过期时间比规则配置的更短, 以接口声明的为准
+		time.Sleep(time.Millisecond * 250)
+		if n := hits(req()); n != 1 {
+			t.Errorf("接口声明的过期时间已到, 期望重新请求, 处理器调用次数: %d", n)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// 请求合并功能, 相同 cacheKey 的请求同时到达时, 只有第一个请求会访问上游,
+// 其余请求等待其响应后直接复用
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CoalesceTimeout 等待相同请求响应的最长时间, 超时后自行请求上游
+var CoalesceTimeout = time.Second * 10
+
+// call 正在处理中的请求
+type call struct {
+	done chan struct{} // 请求处理完毕时关闭
+	rc   *respCache    // 请求的响应, 响应不可缓存时为 nil
+	once sync.Once
+}
+
+var (
+	callsMu sync.Mutex
+
+	// calls 正在处理中的请求, cacheKey => call
+	calls = make(map[string]*call)
+)
+
+// joinCall 加入 cacheKey 对应的处理中请求
+//
+// 不存在处理中的请求时, 会新建一个请求, 并返回 leader = true,
+// 调用方处理完毕后需要调用 finish
+func joinCall(cacheKey string) (cl *call, leader bool) {
+	callsMu.Lock()
+	defer callsMu.Unlock()
+	if cl, ok := calls[cacheKey]; ok {
+		return cl, false
+	}
+	cl = &call{done: make(chan struct{})}
+	calls[cacheKey] = cl
+	return cl, true
+}
+
+// finish 请求处理完毕, 唤醒所有等待中的请求
+//
+// 重复调用时, 只有第一次调用生效
+func (cl *call) finish(cacheKey string, rc *respCache) {
+	cl.once.Do(func() {
+		callsMu.Lock()
+		if calls[cacheKey] == cl {
+			delete(calls, cacheKey)
+		}
+		callsMu.Unlock()
+
+		cl.rc = rc
+		close(cl.done)
+	})
+}
+
+// wait 等待请求处理完毕
+//
+// 等待超时, 客户端取消请求, 或者响应不可缓存时, 返回 false
+func (cl *call) wait(ctx context.Context) (*respCache, bool) {
+	timer := time.NewTimer(CoalesceTimeout)
+	defer timer.Stop()
+	select {
+	case <-cl.done:
+		return cl.rc, cl.rc != nil
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
@@ -0,0 +1,191 @@
+package cache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCoalesce(t *testing.T) {
+	initConfig(t, `  routes:
+    - pattern: (?i)^/coalesce
+`)
+	defer func(timeout time.Duration) { cache.CoalesceTimeout = timeout }(cache.CoalesceTimeout)
+
+	var (
+		mu      sync.Mutex
+		release chan struct{}
+		// first 只有第一个到达处理器的请求会被阻塞
+		first atomic.Bool
+	)
+	// block 阻塞处理器, 直到当前用例唤醒
+	block := func() {
+		mu.Lock()
+		ch := release
+		mu.Unlock()
+		<-ch
+	}
+	f := newFixture("")
+	f.handle("/coalesce/ok", func(c *gin.Context) {
+		block()
+		c.String(http.StatusCreated, "leader body")
+	})
+	f.handle("/coalesce/redirect", func(c *gin.Context) {
+		block()
+		c.Redirect(http.StatusTemporaryRedirect, "http://example.com/direct")
+	})
+	f.handle("/coalesce/nocache", func(c *gin.Context) {
+		// 只有第一个请求会阻塞, 响应体写出后才会唤醒等待中的请求
+		if first.CompareAndSwap(false, true) {
+			c.Header(cache.HeaderKeyExpired, "-1")
+			c.String(http.StatusOK, "stream")
+			block()
+			return
+		}
+		c.String(http.StatusOK, "self")
+	})
+	f.handle("/coalesce/stall", func(c *gin.Context) {
+		if first.CompareAndSwap(false, true) {
+			block()
+		}
+		c.String(http.StatusOK, "self")
+	})
+
+	// concurrently 并发发起 n 个相同请求, 返回所有请求的响应
+	concurrently := func(uri string, n int) []*httptest.ResponseRecorder {
+		ws := make([]*httptest.ResponseRecorder, n)
+		var wg sync.WaitGroup
+		for i := range n {
+			ws[i] = httptest.NewRecorder()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f.ServeHTTP(ws[i], httptest.NewRequest(http.MethodGet, uri, nil))
+			}()
+		}
+		wg.Wait()
+		return ws
+	}
+
+	// reset 重置计数并阻塞处理器, 返回唤醒处理器的函数
+	//
+	// 请求地址需要拼接 nonce, 避免复用之前用例的缓存
+	reset := func() (unblock func(), nonce string) {
+		f.calls.Store(0)
+		first.Store(false)
+		ch := make(chan struct{})
+		mu.Lock()
+		release = ch
+		mu.Unlock()
+		return sync.OnceFunc(func() { close(ch) }), "?nonce=" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	t.Run("follower replays leader response", func(t *testing.T) {
+		cache.CoalesceTimeout = time.Second * 10
+		for _, tc := range []struct {
+			uri      string
+			code     int
+			body     string
+			location string
+		}{
+			{uri: "/coalesce/ok", code: http.StatusCreated, body: "leader body"},
+			{uri: "/coalesce/redirect", code: http.StatusTemporaryRedirect, location: "http://example.com/direct"},
+		} {
+			unblock, nonce := reset()
+			before := cache.GetStats().Coalesced
+			time.AfterFunc(time.Millisecond*200, unblock)
+			ws := concurrently(tc.uri+nonce, 8)
+			cache.WaitingForHandleChan()
+
+			if f.calls.Load() != 1 {
+				t.Errorf("%s: 期望只请求一次上游, 实际: %d", tc.uri, f.calls.Load())
+			}
+			if got := cache.GetStats().Coalesced - before; got != 7 {
+				t.Errorf("%s: 期望合并 7 个请求, 实际: %d", tc.uri, got)
+			}
+			for _, w := range ws {
+				if w.Code != tc.code {
+					t.Errorf("%s: 响应码不一致, 期望: %d, 实际: %d", tc.uri, tc.code, w.Code)
+				}
+				if tc.body != "" && w.Body.String() != tc.body {
+					t.Errorf("%s: 响应体不一致: %q", tc.uri, w.Body.String())
+				}
+				if loc := w.Header().Get("Location"); loc != tc.location {
+					t.Errorf("%s: Location 不一致: %q", tc.uri, loc)
+				}
+			}
+		}
+	})
+
+	t.Run("non-cacheable response wakes followers", func(t *testing.T) {
+		cache.CoalesceTimeout = time.Second * 10
+		unblock, nonce := reset()
+		leaderDone := make(chan struct{})
+		defer func() {
+			unblock()
+			<-leaderDone
+			cache.WaitingForHandleChan()
+		}()
+		go func() {
+			defer close(leaderDone)
+			f.get("/coalesce/nocache" + nonce)
+		}()
+		for f.calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		start := time.Now()
+		for _, w := range concurrently("/coalesce/nocache"+nonce, 4) {
+			if w.Body.String() != "self" {
+				t.Errorf("期望自行请求上游, 响应体: %q", w.Body.String())
+			}
+		}
+		if elapsed := time.Since(start); elapsed >= time.Second {
+			t.Errorf("响应不可缓存时应该立即唤醒等待中的请求, 实际等待: %v", elapsed)
+		}
+		select {
+		case <-leaderDone:
+			t.Fatal("leader 不应提前结束")
+		default:
+		}
+	})
+
+	t.Run("stalled leader times out", func(t *testing.T) {
+		cache.CoalesceTimeout = time.Millisecond * 100
+		unblock, nonce := reset()
+		leaderDone := make(chan struct{})
+		defer func() {
+			unblock()
+			<-leaderDone
+			cache.WaitingForHandleChan()
+		}()
+		go func() {
+			defer close(leaderDone)
+			f.get("/coalesce/stall" + nonce)
+		}()
+		for f.calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		start := time.Now()
+		for _, w := range concurrently("/coalesce/stall"+nonce, 4) {
+			if w.Body.String() != "self" {
+				t.Errorf("期望超时后自行请求上游, 响应体: %q", w.Body.String())
+			}
+		}
+		if elapsed := time.Since(start); elapsed < cache.CoalesceTimeout {
+			t.Errorf("期望等待 leader 直到超时, 实际等待: %v", elapsed)
+		}
+		if f.calls.Load() != 5 {
+			t.Errorf("期望超时后每个请求都访问上游, 实际: %d", f.calls.Load())
+		}
+	})
+}
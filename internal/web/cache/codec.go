@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// credentialHeaders 携带客户端凭证的请求头, 不写入磁盘以及 redis
+var credentialHeaders = []string{"X-Emby-Token", "X-Emby-Authorization", "X-MediaBrowser-Token", "Authorization", "Cookie"}
+
+// credentialParams 携带客户端凭证的请求参数, 不写入磁盘以及 redis, 名称不区分大小写
+var credentialParams = []string{"api_key", "X-Emby-Token", "X-MediaBrowser-Token"}
+
+// cacheEntry 缓存对象序列化后的存储格式, 用于磁盘缓存以及 redis 缓存
+type cacheEntry struct {
+	CacheKey      string
+	Code          int
+	Body          []byte
+	Gzip          bool
+	Uri           string
+	Expired       int64
+	Stale         int64
+	HeaderExpired string
+	HeaderStale   string
+	Space         string
+	SpaceKey      string
+	Header        http.Header
+	ReqMethod     string
+	ReqHeader     http.Header
+	ReqBody       []byte
+}
+
+// encodeCache 将缓存对象序列化
+//
+// 原始请求中的客户端凭证不参与序列化, 后台刷新时从触发刷新的请求中重新注入
+func encodeCache(rc *respCache) ([]byte, error) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	reqHeader, uri := stripCredentials(rc.req.header, rc.uri)
+	ce := cacheEntry{
+		CacheKey:      rc.cacheKey,
+		Code:          rc.code,
+		Body:          rc.body,
+		Gzip:          rc.gzip,
+		Uri:           uri,
+		Expired:       rc.expired,
+		Stale:         rc.stale,
+		HeaderExpired: rc.header.expired,
+		HeaderStale:   rc.header.stale,
+		Space:         rc.header.space,
+		SpaceKey:      rc.header.spaceKey,
+		Header:        rc.header.header,
+		ReqMethod:     rc.req.method,
+		ReqHeader:     reqHeader,
+		ReqBody:       rc.req.body,
+	}
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(&ce); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeCache 将序列化的数据还原为缓存对象
+func decodeCache(data []byte) (*respCache, error) {
+	var ce cacheEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&ce); err != nil {
+		return nil, err
+	}
+	if ce.Body == nil {
+		ce.Body = []byte{}
+	}
+	if ce.Header == nil {
+		ce.Header = make(http.Header)
+	}
+	return &respCache{
+		code:     ce.Code,
+		body:     ce.Body,
+		gzip:     ce.Gzip,
+		cacheKey: ce.CacheKey,
+		uri:      ce.Uri,
+		expired:  ce.Expired,
+		stale:    ce.Stale,
+		req:      replayRequest{method: ce.ReqMethod, header: ce.ReqHeader, body: ce.ReqBody},
+		header: respHeader{
+			expired:  ce.HeaderExpired,
+			stale:    ce.HeaderStale,
+			space:    ce.Space,
+			spaceKey: ce.SpaceKey,
+			header:   ce.Header,
+		},
+	}, nil
+}
+
+// stripCredentials 返回移除客户端凭证后的请求头以及请求地址
+func stripCredentials(header http.Header, uri string) (http.Header, string) {
+	if header != nil {
+		header = header.Clone()
+		for _, key := range credentialHeaders {
+			header.Del(key)
+		}
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil || u.RawQuery == "" {
+		return header, uri
+	}
+	q := u.Query()
+	for key := range q {
+		if isCredentialParam(key) {
+			q.Del(key)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return header, u.String()
+}
+
+// isCredentialParam 判断请求参数是否携带客户端凭证
+func isCredentialParam(key string) bool {
+	for _, p := range credentialParams {
+		if strings.EqualFold(p, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+// 响应体压缩存储功能, 缓存的响应体使用 gzip 压缩后存放,
+// 客户端支持 gzip 时直接响应压缩后的数据, 否则解压后响应
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// CompressMinSize 响应体超过该大小时才进行压缩 (Byte)
+const CompressMinSize = 1 << 10
+
+// incompressibleTypes 本身已经是压缩格式的响应类型前缀, 不进行压缩
+var incompressibleTypes = []string{"image/", "video/", "audio/", "application/zip", "application/gzip"}
+
+// compressBody 尝试压缩响应体, 压缩后体积没有减小时返回原始响应体
+//
+// 返回的 bool 表示响应体是否被压缩
+func compressBody(body []byte, header http.Header) ([]byte, bool) {
+	if len(body) < CompressMinSize || header.Get("Content-Encoding") != "" {
+		return body, false
+	}
+	ct := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(ct, prefix) {
+			return body, false
+		}
+	}
+
+	buf := bytes.Buffer{}
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(body); err != nil {
+		return body, false
+	}
+	if err := gw.Close(); err != nil {
+		return body, false
+	}
+	if buf.Len() >= len(body) {
+		return body, false
+	}
+	return buf.Bytes(), true
+}
+
+// decompressBody 解压响应体
+func decompressBody(body []byte) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	return io.ReadAll(gr)
+}
+
+// compress 压缩缓存的响应体, 已经压缩过的缓存不做处理
+func (c *respCache) compress() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gzip {
+		return
+	}
+	c.body, c.gzip = compressBody(c.body, c.header.header)
+}
+
+// plainBody 获取未压缩的响应体, 未压缩的缓存直接返回内部的响应体
+//
+// 需要在持有锁的情况下调用
+func (c *respCache) plainBody() []byte {
+	if !c.gzip {
+		return c.body
+	}
+	body, err := decompressBody(c.body)
+	if err != nil {
+		logs.Warn("缓存响应体解压失败: %v, cacheKey: %s", err, c.cacheKey)
+		return []byte{}
+	}
+	return body
+}
+
+// writeBody 将缓存的响应体写出到客户端
+//
+// 客户端支持 gzip 时直接写出压缩后的数据, 并修正响应头
+func (c *respCache) writeBody(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.gzip {
+		w.Write(c.body)
+		return
+	}
+
+	if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(len(c.body)))
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.Write(c.body)
+		return
+	}
+	body := c.plainBody()
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
+
+// acceptsGzip 判断 Accept-Encoding 请求头是否允许 gzip 编码
+//
+// 显式声明的 gzip 优先于通配符 *
+func acceptsGzip(acceptEncoding string) bool {
+	gzipQ, starQ := -1.0, -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip":
+			gzipQ = q
+		case "*":
+			starQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return starQ > 0
+}
@@ -0,0 +1,91 @@
+package cache_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompressedCache(t *testing.T) {
+	initConfig(t, `  routes:
+    - pattern: (?i)^/compress
+`)
+
+	body := `{"Items":[` + strings.Repeat(`{"Name":"episode","Type":"Episode","MediaType":"Video"},`, 500) + `{}]}`
+	f := newFixture("Compress")
+	f.handle("/compress/:name", func(c *gin.Context) {
+		if c.Param("name") == "image" {
+			c.Data(http.StatusOK, "image/jpeg", []byte(body))
+			return
+		}
+		c.Data(http.StatusOK, "application/json", []byte(body))
+	})
+	do := func(uri, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, uri, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		return f.do(req)
+	}
+
+	do("/compress/json", "")
+	cache.WaitingForHandleChan()
+
+	// 客户端支持 gzip 时直接响应压缩后的数据
+	w := do("/compress/json", "deflate, gzip;q=0.8")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Body.Len() >= len(body) {
+		t.Fatalf("期望响应压缩后的数据, 响应头: %v, 大小: %d", w.Header(), w.Body.Len())
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, _ := io.ReadAll(gr); string(plain) != body {
+		t.Fatal("解压后的响应体与原始响应体不一致")
+	}
+
+	// 客户端不支持 gzip 时解压后响应
+	for _, ae := range []string{"", "gzip;q=0, *"} {
+		w = do("/compress/json", ae)
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != body {
+			t.Errorf("Accept-Encoding: %q, 期望响应原始数据, 响应头: %v", ae, w.Header())
+		}
+	}
+	if f.calls.Load() != 1 {
+		t.Fatalf("期望命中缓存, 处理器调用次数: %d", f.calls.Load())
+	}
+
+	// 缓存容量按照压缩后的大小计算
+	if stats := cache.GetStats(); stats.Size <= 0 || stats.Size >= int64(len(body))/10 {
+		t.Errorf("缓存大小异常: %d, 原始大小: %d", stats.Size, len(body))
+	}
+
+	// 读取缓存时返回解压后的响应体, 更新后重新压缩
+	rc, ok := cache.GetSpaceCache("Compress", "json")
+	if !ok || string(rc.BodyBytes()) != body {
+		t.Fatal("读取缓存空间失败")
+	}
+	rc.Update(0, []byte(strings.ToUpper(body)), nil)
+	if w = do("/compress/json", "gzip"); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("更新后期望继续压缩存储, 响应头: %v", w.Header())
+	}
+	if item, err := rc.JsonBody(); err != nil || item == nil {
+		t.Errorf("解析缓存 json 失败: %v", err)
+	}
+
+	// 已经是压缩格式的响应不进行压缩
+	do("/compress/image", "")
+	cache.WaitingForHandleChan()
+	if w = do("/compress/image", "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != body {
+		t.Errorf("图片响应不应被压缩, 响应头: %v", w.Header())
+	}
+	cache.PurgeSpace("Compress", "")
+}
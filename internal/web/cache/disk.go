@@ -0,0 +1,260 @@
+// 磁盘缓存功能, 将内存中的缓存同步写入磁盘
+// 程序重启后重新加载未过期的缓存, 避免重启后集中请求上游服务
+package cache
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// DiskFileExt 磁盘缓存文件的扩展名
+const DiskFileExt = ".cache"
+
+// disk 磁盘缓存, 未启用时为 nil
+var disk atomic.Pointer[diskStore]
+
+// diskFile 磁盘中缓存文件的索引信息
+type diskFile struct {
+	size    int64 // 文件大小
+	modTime int64 // 最后写入时间 UnixMilli
+}
+
+// diskStore 磁盘缓存存储
+type diskStore struct {
+	mu    sync.Mutex
+	dir   string              // 缓存文件存放目录
+	size  int64               // 缓存文件总大小
+	files map[string]diskFile // cacheKey => 文件信息
+}
+
+// InitDisk 初始化磁盘缓存, 并将磁盘中未过期的缓存加载到内存中
+//
+// 未启用磁盘缓存时不做处理
+func InitDisk() error {
+	dc := config.C().Cache.Disk
+	if !dc.Enable {
+		return nil
+	}
+	if storage.name() != config.CacheBackendMemory {
+		logs.Warn("缓存存储后端为 %s, 磁盘缓存不生效", storage.name())
+		return nil
+	}
+
+	d := &diskStore{dir: dc.DirPath(), files: make(map[string]diskFile)}
+	if err := os.MkdirAll(d.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("创建磁盘缓存目录失败: %v", err)
+	}
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("读取磁盘缓存目录失败: %v", err)
+	}
+
+	type loadedFile struct {
+		rc   *respCache
+		info diskFile
+	}
+	files := make([]loadedFile, 0, len(entries))
+	expired := 0
+	nowMillis := time.Now().UnixMilli()
+	for _, entry := range entries {
+		name := entry.Name()
+		fp := filepath.Join(d.dir, name)
+		if entry.IsDir() {
+			continue
+		}
+		// 清理上次退出时未写入完成的临时文件
+		if strings.HasSuffix(name, DiskFileExt+".tmp") {
+			os.Remove(fp)
+			continue
+		}
+		if !strings.HasSuffix(name, DiskFileExt) {
+			continue
+		}
+		rc, info, err := readDiskFile(fp)
+		if err != nil {
+			logs.Warn("磁盘缓存文件 [%s] 已损坏, 将被删除: %v", name, err)
+			os.Remove(fp)
+			continue
+		}
+		if nowMillis > rc.deadline() {
+			os.Remove(fp)
+			expired++
+			continue
+		}
+		files = append(files, loadedFile{rc: rc, info: info})
+	}
+
+	// 按照写入时间从旧到新加载, 超出内存容量时优先保留最新的缓存
+	slices.SortFunc(files, func(a, b loadedFile) int {
+		return cmp.Compare(a.info.modTime, b.info.modTime)
+	})
+	loaded := 0
+	for _, f := range files {
+		d.files[f.rc.cacheKey] = f.info
+		d.size += f.info.size
+		evicted, ok := storeCache(f.rc)
+		if !ok {
+			// 内存容量调小后, 单个缓存已经超出容量上限
+			d.remove(f.rc.cacheKey)
+			continue
+		}
+		loaded++
+		for _, ev := range evicted {
+			d.remove(ev.cacheKey)
+			loaded--
+		}
+	}
+
+	d.shrink()
+	disk.Store(d)
+	logs.Success("磁盘缓存加载完成, 有效缓存: %d, 清理过期缓存: %d, 目录: %s", loaded, expired, d.dir)
+	return nil
+}
+
+// readDiskFile 读取并解析磁盘中的缓存文件
+func readDiskFile(fp string) (*respCache, diskFile, error) {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, diskFile{}, err
+	}
+	rc, err := decodeCache(data)
+	if err != nil {
+		return nil, diskFile{}, err
+	}
+	if rc.cacheKey+DiskFileExt != filepath.Base(fp) {
+		return nil, diskFile{}, fmt.Errorf("cacheKey 与文件名不匹配")
+	}
+
+	var modTime int64
+	if stat, err := os.Stat(fp); err == nil {
+		modTime = stat.ModTime().UnixMilli()
+	}
+	return rc, diskFile{size: int64(len(data)), modTime: modTime}, nil
+}
+
+// persistCache 将缓存对象写入磁盘
+//
+// 未启用磁盘缓存, 或者缓存已经被淘汰时, 不做处理
+func persistCache(rc *respCache) {
+	d := disk.Load()
+	if d == nil || rc == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// 在锁内判断, 避免与移除缓存并发时残留已淘汰的缓存文件
+	if cur, ok := store.peek(rc.cacheKey); !ok || cur != rc {
+		return
+	}
+
+	data, err := encodeCache(rc)
+	if err != nil {
+		logs.Warn("磁盘缓存序列化失败: %v", err)
+		return
+	}
+
+	if err := d.write(rc.cacheKey, data); err != nil {
+		logs.Warn("磁盘缓存写入失败: %v", err)
+		return
+	}
+	d.shrink()
+}
+
+// unpersistCache 删除磁盘中的缓存
+func unpersistCache(cacheKey string) {
+	d := disk.Load()
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.remove(cacheKey)
+}
+
+// diskUsage 获取磁盘缓存的文件数以及总大小
+func diskUsage() (int, int64) {
+	d := disk.Load()
+	if d == nil {
+		return 0, 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.files), d.size
+}
+
+// write 写入缓存文件, 先写临时文件再重命名, 避免程序中途退出导致文件损坏
+//
+// 缓存文件中包含用户的媒体信息, 只允许当前用户读写
+//
+// 需要在持有锁的情况下调用
+func (d *diskStore) write(cacheKey string, data []byte) error {
+	fp := filepath.Join(d.dir, cacheKey+DiskFileExt)
+	tmp := fp + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, fp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if old, ok := d.files[cacheKey]; ok {
+		d.size -= old.size
+	}
+	size := int64(len(data))
+	d.files[cacheKey] = diskFile{size: size, modTime: time.Now().UnixMilli()}
+	d.size += size
+	return nil
+}
+
+// remove 删除缓存文件
+//
+// 需要在持有锁的情况下调用
+func (d *diskStore) remove(cacheKey string) {
+	info, ok := d.files[cacheKey]
+	if !ok {
+		return
+	}
+	if err := os.Remove(filepath.Join(d.dir, cacheKey+DiskFileExt)); err != nil && !os.IsNotExist(err) {
+		logs.Warn("删除磁盘缓存文件失败: %v", err)
+	}
+	delete(d.files, cacheKey)
+	d.size -= info.size
+}
+
+// shrink 磁盘缓存超出容量上限时, 优先删除最早写入的缓存文件
+//
+// 被删除的缓存仍保留在内存中, 只是不会在重启后被加载
+//
+// 需要在持有锁的情况下调用
+func (d *diskStore) shrink() {
+	maxSize := config.C().Cache.Disk.MaxSizeBytes()
+	if maxSize <= 0 || d.size <= maxSize {
+		return
+	}
+
+	keys := make([]string, 0, len(d.files))
+	for key := range d.files {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Compare(d.files[a].modTime, d.files[b].modTime)
+	})
+	for _, key := range keys {
+		if d.size <= maxSize {
+			break
+		}
+		d.remove(key)
+	}
+}
@@ -0,0 +1,126 @@
+package cache_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initDisk 初始化启用磁盘缓存的配置, 返回会将响应写入缓存空间 Disk 的路由
+//
+// 请求携带 ttl 参数时, 使用 ttl 作为缓存过期时间
+func initDisk(t *testing.T, maxSize string) *fixture {
+	initConfig(t, fmt.Sprintf(`  disk:
+    enable: true
+    dir: %s
+    max-size: %s
+  routes:
+    - pattern: (?i)^/disk
+`, t.TempDir(), maxSize))
+	resetStore(t)
+	if err := cache.InitDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFixture("Disk")
+	f.handle("/disk/:name", func(c *gin.Context) {
+		if ttl, err := time.ParseDuration(c.Query("ttl")); err == nil {
+			c.Header(cache.HeaderKeyExpired, cache.Duration(ttl))
+		}
+		c.String(http.StatusOK, diskBody(c.Param("name")))
+	})
+	return f
+}
+
+// diskBody 生成不会被压缩的响应体
+func diskBody(name string) string {
+	return strings.Repeat(name+"|", 500/(len(name)+1))
+}
+
+// restart 清空内存缓存后重新从磁盘加载, 模拟程序重启
+func restart(t *testing.T) {
+	cache.WaitingForHandleChan()
+	cache.ResetStore()
+	if err := cache.InitDisk(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiskReload(t *testing.T) {
+	f := initDisk(t, "1m")
+	do := func(uri string) { f.get(uri) }
+
+	do("/disk/a")
+	do("/disk/b")
+	do("/disk/short?ttl=100ms")
+	cache.WaitingForHandleChan()
+	if stats := cache.GetStats(); stats.DiskCount != 3 {
+		t.Fatalf("期望写入 3 个磁盘缓存文件, 实际: %d", stats.DiskCount)
+	}
+	time.Sleep(time.Millisecond * 150)
+
+	restart(t)
+
+	// 缓存空间信息随缓存一起持久化
+	for _, name := range []string{"a", "b"} {
+		rc, ok := cache.GetSpaceCache("Disk", name)
+		if !ok {
+			t.Fatalf("重启后缓存空间 [%s] 丢失", name)
+		}
+		if rc.Space() != "Disk" || rc.SpaceKey() != name || string(rc.BodyBytes()) != diskBody(name) {
+			t.Errorf("重启后缓存 [%s] 不一致, space: %s, spaceKey: %s", name, rc.Space(), rc.SpaceKey())
+		}
+	}
+
+	// 过期的缓存在加载时被清理
+	if _, ok := cache.GetSpaceCache("Disk", "short"); ok {
+		t.Error("过期的缓存不应被加载")
+	}
+	if stats := cache.GetStats(); stats.DiskCount != 2 || stats.Count != 2 {
+		t.Errorf("期望加载 2 个缓存, 磁盘: %d, 内存: %d", stats.DiskCount, stats.Count)
+	}
+
+	// 重启后直接命中缓存
+	before := f.calls.Load()
+	do("/disk/a")
+	if f.calls.Load() != before {
+		t.Error("重启后期望命中缓存")
+	}
+}
+
+func TestDiskQuota(t *testing.T) {
+	f := initDisk(t, "3k")
+	names := []string{"n1", "n2", "n3", "n4", "n5", "n6"}
+	for _, name := range names {
+		f.get("/disk/" + name)
+		cache.WaitingForHandleChan()
+		// 保证文件的写入时间不同
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	stats := cache.GetStats()
+	if stats.DiskSize > 3<<10 || stats.DiskCount == 0 || stats.DiskCount >= len(names) {
+		t.Fatalf("磁盘缓存超出容量上限, 文件数: %d, 大小: %d", stats.DiskCount, stats.DiskSize)
+	}
+	// 超出磁盘容量时内存中的缓存不受影响
+	if stats.Count != len(names) {
+		t.Fatalf("内存缓存条目数异常: %d", stats.Count)
+	}
+
+	restart(t)
+
+	// 优先淘汰最早写入的缓存文件, 保留下来的应该是最新的若干个
+	kept := len(names) - stats.DiskCount
+	for i, name := range names {
+		_, ok := cache.GetSpaceCache("Disk", name)
+		if want := i >= kept; ok != want {
+			t.Errorf("缓存 [%s] 加载结果异常, 期望: %v, 实际: %v", name, want, ok)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package cache
+
+// ResetStore 清空内存缓存并关闭磁盘缓存, 用于模拟程序重启
+//
+// 调用前需要确保没有正在写入的缓存
+func ResetStore() {
+	store = newLruStore()
+	spaceMap.Clear()
+	disk.Store(nil)
+}
@@ -0,0 +1,88 @@
+package cache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initConfig 初始化测试配置, cacheCfg 为 cache 节点下的配置内容
+func initConfig(t *testing.T, cacheCfg string) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := `emby:
+  host: http://127.0.0.1:8096
+openlist:
+  host: http://127.0.0.1:5244
+  token: stub-token
+cache:
+  enable: true
+` + cacheCfg
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// resetStore 按照当前配置初始化存储后端, 并在测试前后清空内存缓存
+func resetStore(t *testing.T) {
+	if err := cache.InitBackend(); err != nil {
+		t.Fatal(err)
+	}
+	cache.ResetStore()
+	t.Cleanup(cache.ResetStore)
+}
+
+// fixture 经过缓存中间件的测试路由
+type fixture struct {
+	*gin.Engine
+
+	// calls 处理器被调用的次数
+	calls atomic.Int32
+
+	// space 响应写入的缓存空间名称, 为空时不写入缓存空间
+	space string
+}
+
+// newFixture 初始化测试路由
+//
+// space 不为空时, 响应写入缓存空间 space, 缓存空间 key 默认为路径参数 name
+func newFixture(space string) *fixture {
+	gin.SetMode(gin.TestMode)
+	f := &fixture{Engine: gin.New(), space: space}
+	f.Use(cache.CacheableRouteMarker(), cache.RequestCacher())
+	return f
+}
+
+// handle 注册 GET 请求处理器, 调用处理器之前先计数
+func (f *fixture) handle(path string, handler gin.HandlerFunc) {
+	f.GET(path, func(c *gin.Context) {
+		f.calls.Add(1)
+		if f.space != "" {
+			c.Header(cache.HeaderKeySpace, f.space)
+			c.Header(cache.HeaderKeySpaceKey, c.Param("name"))
+		}
+		handler(c)
+	})
+}
+
+// do 发起请求 req 并返回响应
+func (f *fixture) do(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, req)
+	return w
+}
+
+// get 使用 GET 方法请求 uri 并返回响应
+func (f *fixture) get(uri string) *httptest.ResponseRecorder {
+	return f.do(httptest.NewRequest(http.MethodGet, uri, nil))
+}
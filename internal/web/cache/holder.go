@@ -6,124 +6,113 @@ import (
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 
 	"github.com/gin-gonic/gin"
 )
 
 const (
 
-	// MaxCacheSize 缓存最大大小 (Byte)
-	//
-	// 这里的大小指的是响应体大小, 实际占用大小可能略大一些
-	MaxCacheSize int64 = 100 * 1024 * 1024
-
-	// MaxCacheNum 最多缓存多少个请求信息
-	MaxCacheNum = 8092
-
 	// HeaderKeyExpired 缓存过期响应头, 用于覆盖默认的缓存过期时间
 	HeaderKeyExpired = "Expired"
-)
 
-// currentCacheSize 当前内存中的缓存大小 (Byte)
-var currentCacheSize int64 = 0
+	// CleanInterval 清理过期缓存的时间间隔
+	CleanInterval = time.Second * 10
+)
 
 // DefaultExpired 默认的请求过期时间
 //
 // 可通过设置 "Expired" 响应头进行覆盖
-var DefaultExpired = func() time.Duration { return config.C.Cache.ExpiredDuration() }
-
-// cacheMap 存放缓存数据的 map
-var cacheMap = sync.Map{}
+var DefaultExpired = func() time.Duration { return config.C().Cache.ExpiredDuration() }
 
-// preCacheChan 预缓存通道
-//
-// 缓存数据先暂存在通道中, 再由专门的 goroutine 单线程处理
-//
-// preCacheChan 的淘汰规则是先入先淘汰, 不管缓存对象的过期时间
-var preCacheChan = make(chan *respCache, MaxCacheNum)
+// store 存放缓存数据, 超出 cache.max-size, cache.max-num 配置时淘汰最近最少使用的缓存
+var store = newLruStore()
 
-// cacheHandleWaitGroup 允许等待预缓存通道处理完毕后再获取数据
+// cacheHandleWaitGroup 允许等待异步写入的缓存处理完毕后再获取数据
 var cacheHandleWaitGroup = sync.WaitGroup{}
 
-func init() {
-	go loopMaintainCache()
-}
+var (
+	// stopChan 关闭时通知维护 goroutine 退出
+	stopChan = make(chan struct{})
 
-// loopMaintainCache cacheMap 由单独的 goroutine 维护
-func loopMaintainCache() {
+	// stopped 维护 goroutine 退出时关闭
+	stopped = make(chan struct{})
 
-	// cleanCache 清洗缓存数据
-	cleanCache := func() {
-		validCnt := 0
-		nowMillis := time.Now().UnixMilli()
-		toDelete := make([]*respCache, 0, 1<<3)
-
-		cacheMap.Range(func(key, value any) bool {
-			rc := value.(*respCache)
-			if nowMillis > rc.expired || validCnt == MaxCacheNum || currentCacheSize > MaxCacheSize {
-				toDelete = append(toDelete, rc)
-			} else {
-				validCnt++
-			}
-			return true
-		})
+	stopOnce sync.Once
+)
 
-		for _, rc := range toDelete {
-			cacheMap.Delete(rc.cacheKey)
-			currentCacheSize -= int64(len(rc.body))
-			delSpaceCache(rc.header.space, rc.header.spaceKey)
-		}
-	}
+func init() {
+	go loopMaintainCache()
 
-	// putrespCache 将缓存对象维护到 cacheMap 中
-	//
-	// 同时淘汰掉过期缓存
-	putrespCache := func(rc *respCache) {
-		cacheMap.Store(rc.cacheKey, rc)
-		currentCacheSize += int64(len(rc.body))
-		space, spaceKey := rc.header.space, rc.header.spaceKey
-		if strs.AllNotEmpty(space, spaceKey) {
-			putSpaceCache(space, spaceKey, rc)
-		}
-	}
+	// 容量配置调小后, 立即淘汰超出的缓存
+	config.OnReload(func() { handleEvicted(store.evictOverflow()) })
+}
 
-	timer := time.NewTicker(time.Second * 10)
+// Stop 停止缓存维护 goroutine, 正在写入的缓存会在退出前处理完毕
+func Stop() {
+	stopOnce.Do(func() { close(stopChan) })
+	<-stopped
+	cacheHandleWaitGroup.Wait()
+	storage.close()
+}
+
+// loopMaintainCache 定时清理过期缓存
+func loopMaintainCache() {
+	timer := time.NewTicker(CleanInterval)
 	defer timer.Stop()
+	defer close(stopped)
 	for {
 		select {
-		case rc := <-preCacheChan:
-			putrespCache(rc)
-			cacheHandleWaitGroup.Done()
 		case <-timer.C:
-			cleanCache()
+			for _, rc := range store.removeExpired(time.Now().UnixMilli()) {
+				evictions.Add(1)
+				unpersistCache(rc.cacheKey)
+			}
+		case <-stopChan:
+			return
 		}
 	}
 }
 
-// getCache 根据 cacheKey 获取缓存
-func getCache(cacheKey string) (*respCache, bool) {
-	if c, ok := cacheMap.Load(cacheKey); ok {
-		return c.(*respCache), true
+// storeCache 将缓存对象维护到内存中, 已存在相同 cacheKey 的缓存时, 旧缓存会被覆盖
+//
+// 返回因超出容量而被淘汰的缓存; 缓存超出容量上限无法写入时, 返回 ok = false
+func storeCache(rc *respCache) (evicted []*respCache, ok bool) {
+	evicted, ok = store.put(rc, int64(len(rc.body)))
+	handleEvicted(evicted)
+	return evicted, ok
+}
+
+// resizeCache 缓存的响应体被修改后, 重新计算缓存大小
+func resizeCache(rc *respCache, size int64) {
+	handleEvicted(store.resize(rc, size))
+}
+
+// handleEvicted 统计被淘汰的缓存, 并删除磁盘中的缓存文件
+func handleEvicted(evicted []*respCache) {
+	for _, rc := range evicted {
+		evictions.Add(1)
+		unpersistCache(rc.cacheKey)
 	}
-	return nil, false
 }
 
-// putCache 设置缓存
-func putCache(cacheKey string, c *gin.Context, respBody []byte, respHeader respHeader) {
+// newRespCache 根据请求的响应初始化缓存对象
+//
+// 响应头中的 "Expired" 小于 0 时, 表示不缓存, 返回 false
+func newRespCache(cacheKey string, c *gin.Context, respBody []byte, respHeader respHeader, req replayRequest) (*respCache, bool) {
 	if cacheKey == "" || c == nil || respBody == nil {
-		return
+		return nil, false
 	}
 
 	// 计算缓存过期时间
 	nowMillis := time.Now().UnixMilli()
-	expiredMillis := int64(DefaultExpired()) + nowMillis
+	expiredMillis := DefaultExpired().Milliseconds() + nowMillis
+	var customMillis int64
 	if expiredNum, err := strconv.Atoi(respHeader.expired); err == nil {
-		customMillis := int64(expiredNum)
+		customMillis = int64(expiredNum)
 
 		// 特定接口不使用缓存
 		if customMillis < 0 {
-			return
+			return nil, false
 		}
 
 		if customMillis > nowMillis {
@@ -131,24 +120,38 @@ func putCache(cacheKey string, c *gin.Context, respBody []byte, respHeader respH
 		}
 	}
 
-	rc := &respCache{
+	// 自定义缓存规则配置的过期时间会覆盖默认的过期时间,
+	// 但不能超过接口自身声明的过期时间 (如直链的有效期)
+	if route, ok := cacheRoute(c); ok && route.ExpiredDuration() > 0 {
+		expiredMillis = nowMillis + route.ExpiredDuration().Milliseconds()
+		if customMillis > nowMillis {
+			expiredMillis = min(expiredMillis, customMillis)
+		}
+	}
+
+	// 过期后仍可继续使用的时长
+	var staleMillis int64
+	if staleNum, err := strconv.ParseInt(respHeader.stale, 10, 64); err == nil && staleNum > 0 {
+		staleMillis = expiredMillis + staleNum
+	}
+
+	return &respCache{
 		code:     c.Writer.Status(),
 		body:     respBody,
 		cacheKey: cacheKey,
+		uri:      c.Request.RequestURI,
 		expired:  expiredMillis,
+		stale:    staleMillis,
+		req:      req,
 		header:   respHeader,
-	}
+	}, true
+}
 
-	// 依据先进先淘汰原则, 将最新缓存放入预缓存通道中
-	cacheHandleWaitGroup.Add(1)
-	doneOnce := sync.OnceFunc(cacheHandleWaitGroup.Done)
-	for {
-		select {
-		case preCacheChan <- rc:
-			return
-		default:
-			<-preCacheChan
-			doneOnce()
-		}
-	}
+// putCache 压缩响应体后设置缓存
+//
+// 调用方需要预先调用 cacheHandleWaitGroup.Add(1)
+func putCache(rc *respCache) {
+	defer cacheHandleWaitGroup.Done()
+	rc.compress()
+	storage.put(rc)
 }
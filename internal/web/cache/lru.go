@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+// lruStore 按照最近最少使用原则淘汰的缓存存储
+//
+// 缓存空间的引用在持有锁的情况下同步维护, 保证缓存空间中不会残留已淘汰的缓存
+type lruStore struct {
+	mu    sync.Mutex
+	ll    *list.List               // 缓存链表, 头部为最近使用的缓存
+	items map[string]*list.Element // cacheKey => 链表节点
+	size  int64                    // 响应体总大小 (压缩后, Byte)
+}
+
+// lruEntry 链表节点存放的数据
+type lruEntry struct {
+	rc   *respCache
+	size int64 // 写入时的响应体大小, 保证淘汰时扣减的大小与写入时一致
+}
+
+// newLruStore 初始化一个空的缓存存储
+func newLruStore() *lruStore {
+	return &lruStore{ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get 获取缓存, 并将缓存标记为最近使用
+func (s *lruStore) get(cacheKey string) (*respCache, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[cacheKey]
+	if !ok {
+		return nil, false
+	}
+	s.ll.MoveToFront(e)
+	return e.Value.(*lruEntry).rc, true
+}
+
+// peek 获取缓存, 不影响淘汰顺序
+func (s *lruStore) peek(cacheKey string) (*respCache, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[cacheKey]
+	if !ok {
+		return nil, false
+	}
+	return e.Value.(*lruEntry).rc, true
+}
+
+// put 写入缓存, 已存在相同 cacheKey 的缓存时进行覆盖
+//
+// 返回因超出容量而被淘汰的缓存; 单个缓存超出容量上限时不写入, 返回 ok = false
+func (s *lruStore) put(rc *respCache, size int64) (evicted []*respCache, ok bool) {
+	// 避免为了放下一个超大的缓存而淘汰所有缓存
+	if tooLarge(size) {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.items[rc.cacheKey]; ok {
+		entry := e.Value.(*lruEntry)
+		delSpaceCache(entry.rc.header.space, entry.rc.header.spaceKey, entry.rc)
+		s.size += size - entry.size
+		entry.rc, entry.size = rc, size
+		s.ll.MoveToFront(e)
+	} else {
+		s.items[rc.cacheKey] = s.ll.PushFront(&lruEntry{rc: rc, size: size})
+		s.size += size
+	}
+	putSpaceCache(rc.header.space, rc.header.spaceKey, rc)
+	return s.shrink(), true
+}
+
+// resize 缓存的响应体被修改后, 重新计算缓存大小
+//
+// 返回因超出容量而被淘汰的缓存
+func (s *lruStore) resize(rc *respCache, size int64) []*respCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[rc.cacheKey]
+	if !ok {
+		return nil
+	}
+	entry := e.Value.(*lruEntry)
+	if entry.rc != rc {
+		return nil
+	}
+	// 更新后单个缓存超出容量上限时, 只淘汰该缓存本身
+	if tooLarge(size) {
+		s.removeElement(e)
+		return []*respCache{rc}
+	}
+	s.size += size - entry.size
+	entry.size = size
+	return s.shrink()
+}
+
+// remove 移除缓存
+//
+// 缓存已经被其他请求覆盖时, 不做处理
+func (s *lruStore) remove(rc *respCache) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[rc.cacheKey]
+	if !ok || e.Value.(*lruEntry).rc != rc {
+		return false
+	}
+	s.removeElement(e)
+	return true
+}
+
+// removeExpired 移除所有彻底失效的缓存, 返回被移除的缓存
+func (s *lruStore) removeExpired(nowMillis int64) []*respCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []*respCache
+	for e := s.ll.Back(); e != nil; {
+		prev := e.Prev()
+		if rc := e.Value.(*lruEntry).rc; nowMillis > rc.deadline() {
+			s.removeElement(e)
+			removed = append(removed, rc)
+		}
+		e = prev
+	}
+	return removed
+}
+
+// evictOverflow 按照当前配置的容量淘汰缓存, 用于配置热重载后立即生效
+func (s *lruStore) evictOverflow() []*respCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shrink()
+}
+
+// snapshot 获取所有缓存的快照, 按照最近使用的顺序排列
+func (s *lruStore) snapshot() []*respCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]*respCache, 0, s.ll.Len())
+	for e := s.ll.Front(); e != nil; e = e.Next() {
+		res = append(res, e.Value.(*lruEntry).rc)
+	}
+	return res
+}
+
+// usage 获取缓存条目数以及响应体总大小
+func (s *lruStore) usage() (int, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len(), s.size
+}
+
+// tooLarge 判断单个缓存是否超出容量上限
+func tooLarge(size int64) bool {
+	maxSize := config.C().Cache.MaxSizeBytes()
+	return maxSize > 0 && size > maxSize
+}
+
+// shrink 从链表尾部开始淘汰缓存, 直到满足容量限制
+//
+// 需要在持有锁的情况下调用
+func (s *lruStore) shrink() []*respCache {
+	maxNum, maxSize := config.C().Cache.MaxNum, config.C().Cache.MaxSizeBytes()
+	var evicted []*respCache
+	for s.ll.Len() > 0 && ((maxNum > 0 && s.ll.Len() > maxNum) || (maxSize > 0 && s.size > maxSize)) {
+		e := s.ll.Back()
+		s.removeElement(e)
+		evicted = append(evicted, e.Value.(*lruEntry).rc)
+	}
+	return evicted
+}
+
+// removeElement 移除链表节点, 同时移除缓存空间中的引用
+//
+// 需要在持有锁的情况下调用
+func (s *lruStore) removeElement(e *list.Element) {
+	entry := e.Value.(*lruEntry)
+	s.ll.Remove(e)
+	delete(s.items, entry.rc.cacheKey)
+	s.size -= entry.size
+	delSpaceCache(entry.rc.header.space, entry.rc.header.spaceKey, entry.rc)
+}
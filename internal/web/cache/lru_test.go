@@ -0,0 +1,171 @@
+package cache_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initLru 初始化指定容量的内存缓存, 返回会将响应写入缓存空间 Lru 的路由
+//
+// 每个响应体的大小均为 300 字节
+func initLru(t *testing.T, limits string) *fixture {
+	initConfig(t, limits+`  routes:
+    - pattern: (?i)^/lru
+`)
+	resetStore(t)
+
+	f := newFixture("Lru")
+	f.handle("/lru/:name", func(c *gin.Context) {
+		c.String(http.StatusOK, strings.Repeat("x", 300))
+	})
+	return f
+}
+
+// lruDo 请求路由并等待缓存写入完成
+func lruDo(f *fixture, name string) {
+	f.get("/lru/" + name)
+	cache.WaitingForHandleChan()
+}
+
+// assertCached 校验缓存空间 Lru 中各个缓存是否存在
+func assertCached(t *testing.T, want map[string]bool) {
+	t.Helper()
+	for name, cached := range want {
+		if _, ok := cache.GetSpaceCache("Lru", name); ok != cached {
+			t.Errorf("缓存 [%s] 是否存在, 期望: %v, 实际: %v", name, cached, ok)
+		}
+	}
+}
+
+func TestLruEvictionOrder(t *testing.T) {
+	f := initLru(t, "  max-num: 3\n")
+	do := func(name string) { lruDo(f, name) }
+	do("a")
+	do("b")
+	do("c")
+
+	// 命中缓存后 a 变为最近使用, 淘汰最久未使用的 b
+	do("a")
+	if f.calls.Load() != 3 {
+		t.Fatalf("期望命中缓存, 处理器调用次数: %d", f.calls.Load())
+	}
+	do("d")
+	assertCached(t, map[string]bool{"a": true, "b": false, "c": true, "d": true})
+
+	do("e")
+	assertCached(t, map[string]bool{"a": true, "c": false, "d": true, "e": true})
+
+	// 淘汰的缓存同时从缓存空间中移除
+	stats := cache.GetStats()
+	if stats.Count != 3 || stats.Spaces["Lru"] != 3 {
+		t.Errorf("缓存条目数异常, 总数: %d, 缓存空间: %d", stats.Count, stats.Spaces["Lru"])
+	}
+	if stats.Size != 900 {
+		t.Errorf("缓存大小异常: %d", stats.Size)
+	}
+}
+
+func TestLruSizeAccounting(t *testing.T) {
+	f := initLru(t, "  max-size: 1k\n")
+	do := func(name string) { lruDo(f, name) }
+	do("a")
+	do("b")
+	do("c")
+	if stats := cache.GetStats(); stats.Count != 3 || stats.Size != 900 {
+		t.Fatalf("缓存统计异常, 条目数: %d, 大小: %d", stats.Count, stats.Size)
+	}
+
+	// 更新响应体后重新计算大小, 超出容量时淘汰最久未使用的缓存
+	rc, ok := cache.GetSpaceCache("Lru", "c")
+	if !ok {
+		t.Fatal("读取缓存空间失败")
+	}
+	rc.Update(0, []byte(strings.Repeat("y", 600)), nil)
+	stats := cache.GetStats()
+	if stats.Size > 1<<10 {
+		t.Fatalf("更新后超出容量上限: %d", stats.Size)
+	}
+	assertCached(t, map[string]bool{"a": false, "c": true})
+
+	// 剩余的缓存大小之和与统计一致
+	var sum int64
+	for _, name := range []string{"a", "b", "c"} {
+		if rc, ok := cache.GetSpaceCache("Lru", name); ok {
+			sum += int64(len(rc.BodyBytes()))
+		}
+	}
+	if sum != stats.Size {
+		t.Errorf("缓存大小统计不一致, 统计: %d, 实际: %d", stats.Size, sum)
+	}
+
+	// 移除缓存后扣减对应的大小
+	cache.PurgeSpace("Lru", "c")
+	if after := cache.GetStats(); after.Size != stats.Size-600 || after.Spaces["Lru"] != stats.Spaces["Lru"]-1 {
+		t.Errorf("移除缓存后统计异常, 大小: %d, 缓存空间: %d", after.Size, after.Spaces["Lru"])
+	}
+}
+
+func TestLruOversized(t *testing.T) {
+	f := initLru(t, "  max-size: 1k\n")
+	// 图片响应不会被压缩, 保证写入缓存的大小超出容量上限
+	f.handle("/lru-big/:name", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/jpeg", make([]byte, 2<<10))
+	})
+	do := func(name string) { lruDo(f, name) }
+	do("a")
+	do("b")
+
+	f.get("/lru-big/big")
+	cache.WaitingForHandleChan()
+	assertCached(t, map[string]bool{"a": true, "b": true, "big": false})
+	if stats := cache.GetStats(); stats.Count != 2 || stats.Size != 600 {
+		t.Fatalf("超大缓存不应影响已有缓存, 条目数: %d, 大小: %d", stats.Count, stats.Size)
+	}
+
+	// 更新后超出容量上限时, 只淘汰被更新的缓存
+	rc, ok := cache.GetSpaceCache("Lru", "b")
+	if !ok {
+		t.Fatal("读取缓存空间失败")
+	}
+	rc.Update(0, make([]byte, 2<<10), http.Header{"Content-Type": {"image/jpeg"}})
+	assertCached(t, map[string]bool{"a": true, "b": false})
+	if stats := cache.GetStats(); stats.Count != 1 || stats.Size != 300 {
+		t.Fatalf("缓存统计异常, 条目数: %d, 大小: %d", stats.Count, stats.Size)
+	}
+}
+
+func TestLruConcurrent(t *testing.T) {
+	f := initLru(t, "  max-num: 8\n  max-size: 4k\n")
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				name := fmt.Sprintf("k%d", (i*7+j)%20)
+				f.get("/lru/" + name)
+				if rc, ok := cache.GetSpaceCache("Lru", name); ok {
+					rc.BodyBytes()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	cache.WaitingForHandleChan()
+
+	stats := cache.GetStats()
+	if stats.Count > 8 || stats.Size > 4<<10 {
+		t.Fatalf("超出容量上限, 条目数: %d, 大小: %d", stats.Count, stats.Size)
+	}
+	if stats.Spaces["Lru"] != stats.Count || stats.Size != int64(stats.Count)*300 {
+		t.Errorf("缓存统计不一致, 条目数: %d, 缓存空间: %d, 大小: %d", stats.Count, stats.Spaces["Lru"], stats.Size)
+	}
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/redis"
+)
+
+const (
+
+	// RedisScanCount 遍历 redis 缓存时单次请求的 key 个数
+	RedisScanCount = 200
+
+	// RedisUsageInterval 统计 redis 缓存使用情况的最小时间间隔, 避免频繁遍历
+	RedisUsageInterval = CleanInterval
+)
+
+// redisBackend redis 存储, 多个实例连接同一个 redis 时共享缓存以及缓存空间
+//
+// key 格式:
+// 缓存: {prefix}entry:{cacheKey} => 序列化后的缓存对象;
+// 缓存空间: {prefix}space:{space}:{spaceKey} => cacheKey;
+// 过期时间与缓存彻底失效的时间一致, 由 redis 负责清理
+type redisBackend struct {
+	client *redis.Client
+	prefix string
+
+	// 缓存使用情况的统计结果
+	usageMu    sync.Mutex
+	usageAt    time.Time
+	usageCount int
+	usageSize  int64
+}
+
+// newRedisBackend 连接 redis 并初始化存储
+func newRedisBackend(cfg config.CacheRedis) (*redisBackend, error) {
+	client := redis.NewClient(redis.Options{Addr: cfg.Addr, Password: cfg.Password, DB: cfg.DB})
+	if err := client.Ping(context.Background()); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("连接 redis 失败: %v", err)
+	}
+	return &redisBackend{client: client, prefix: cfg.Prefix}, nil
+}
+
+func (b *redisBackend) name() string {
+	return config.CacheBackendRedis
+}
+
+func (b *redisBackend) get(cacheKey string) (*respCache, bool) {
+	data, err := b.client.Get(context.Background(), b.entryKey(cacheKey))
+	if err != nil {
+		if !errors.Is(err, redis.ErrNil) {
+			logs.Warn("读取 redis 缓存失败: %v", err)
+		}
+		return nil, false
+	}
+	rc, err := decodeCache(data)
+	if err != nil {
+		logs.Warn("redis 缓存解析失败: %v, cacheKey: %s", err, cacheKey)
+		return nil, false
+	}
+	return rc, true
+}
+
+func (b *redisBackend) getSpace(space, spaceKey string) (*respCache, bool) {
+	cacheKey, err := b.client.Get(context.Background(), b.spaceKey(space, spaceKey))
+	if err != nil {
+		if !errors.Is(err, redis.ErrNil) {
+			logs.Warn("读取 redis 缓存空间失败: %v", err)
+		}
+		return nil, false
+	}
+	rc, ok := b.get(string(cacheKey))
+	// 缓存可能已经被其他不属于该缓存空间的响应覆盖
+	if !ok || rc.header.space != space || rc.header.spaceKey != spaceKey {
+		return nil, false
+	}
+	return rc, true
+}
+
+func (b *redisBackend) put(rc *respCache) {
+	ttl := time.Duration(rc.deadline()-time.Now().UnixMilli()) * time.Millisecond
+	if ttl <= 0 {
+		return
+	}
+	data, err := encodeCache(rc)
+	if err != nil {
+		logs.Warn("redis 缓存序列化失败: %v", err)
+		return
+	}
+
+	ctx := context.Background()
+	if err := b.client.Set(ctx, b.entryKey(rc.cacheKey), data, ttl); err != nil {
+		logs.Warn("写入 redis 缓存失败: %v", err)
+		return
+	}
+	if space, spaceKey := rc.header.space, rc.header.spaceKey; space != "" && spaceKey != "" {
+		if err := b.client.Set(ctx, b.spaceKey(space, spaceKey), []byte(rc.cacheKey), ttl); err != nil {
+			logs.Warn("写入 redis 缓存空间失败: %v", err)
+		}
+	}
+}
+
+func (b *redisBackend) update(rc *respCache) {
+	b.put(rc)
+}
+
+// remove redis 中无法判断缓存是否已经被其他实例覆盖, 会直接删除 cacheKey 对应的缓存
+func (b *redisBackend) remove(rc *respCache) bool {
+	ctx := context.Background()
+	n, err := b.client.Del(ctx, b.entryKey(rc.cacheKey))
+	if err != nil {
+		logs.Warn("删除 redis 缓存失败: %v", err)
+		return false
+	}
+
+	// 只有当缓存空间中存放的仍是该缓存时才会删除, 避免误删新的缓存
+	if space, spaceKey := rc.header.space, rc.header.spaceKey; space != "" && spaceKey != "" {
+		sk := b.spaceKey(space, spaceKey)
+		if cur, err := b.client.Get(ctx, sk); err == nil && string(cur) == rc.cacheKey {
+			b.client.Del(ctx, sk)
+		}
+	}
+	return n > 0
+}
+
+func (b *redisBackend) rangeAll(fn func(rc *respCache) bool) {
+	entryPrefix := b.prefix + "entry:"
+	err := b.client.Scan(context.Background(), escapeGlob(entryPrefix)+"*", RedisScanCount, func(key string) bool {
+		rc, ok := b.get(strings.TrimPrefix(key, entryPrefix))
+		if !ok {
+			return true
+		}
+		return fn(rc)
+	})
+	if err != nil {
+		logs.Warn("遍历 redis 缓存失败: %v", err)
+	}
+}
+
+func (b *redisBackend) rangeSpace(space string, fn func(spaceKey string, rc *respCache) bool) {
+	spacePrefix := b.spaceKey(space, "")
+	err := b.client.Scan(context.Background(), escapeGlob(spacePrefix)+"*", RedisScanCount, func(key string) bool {
+		spaceKey := strings.TrimPrefix(key, spacePrefix)
+		rc, ok := b.getSpace(space, spaceKey)
+		if !ok {
+			return true
+		}
+		return fn(spaceKey, rc)
+	})
+	if err != nil {
+		logs.Warn("遍历 redis 缓存空间失败: %v", err)
+	}
+}
+
+// usage 总大小为序列化后的缓存对象大小, 统计结果最多每 RedisUsageInterval 更新一次
+func (b *redisBackend) usage() (int, int64) {
+	b.usageMu.Lock()
+	defer b.usageMu.Unlock()
+	if time.Since(b.usageAt) < RedisUsageInterval {
+		return b.usageCount, b.usageSize
+	}
+
+	ctx := context.Background()
+	cnt, size := 0, int64(0)
+	err := b.client.Scan(ctx, escapeGlob(b.prefix+"entry:")+"*", RedisScanCount, func(key string) bool {
+		res, err := b.client.Do(ctx, "STRLEN", key)
+		if err != nil {
+			return true
+		}
+		if n, ok := res.(int64); ok && n > 0 {
+			cnt++
+			size += n
+		}
+		return true
+	})
+	if err != nil {
+		logs.Warn("统计 redis 缓存失败: %v", err)
+		return b.usageCount, b.usageSize
+	}
+	b.usageAt, b.usageCount, b.usageSize = time.Now(), cnt, size
+	return cnt, size
+}
+
+func (b *redisBackend) spaces() map[string]int {
+	res := make(map[string]int)
+	spacePrefix := b.prefix + "space:"
+	err := b.client.Scan(context.Background(), escapeGlob(spacePrefix)+"*", RedisScanCount, func(key string) bool {
+		space, _, ok := strings.Cut(strings.TrimPrefix(key, spacePrefix), ":")
+		if ok {
+			res[space]++
+		}
+		return true
+	})
+	if err != nil {
+		logs.Warn("统计 redis 缓存空间失败: %v", err)
+	}
+	return res
+}
+
+func (b *redisBackend) close() {
+	b.client.Close()
+}
+
+// entryKey 缓存在 redis 中的 key
+func (b *redisBackend) entryKey(cacheKey string) string {
+	return b.prefix + "entry:" + cacheKey
+}
+
+// spaceKey 缓存空间在 redis 中的 key
+func (b *redisBackend) spaceKey(space, spaceKey string) string {
+	return b.prefix + "space:" + space + ":" + spaceKey
+}
+
+// escapeGlob 转义 redis glob 表达式中的特殊字符
+func escapeGlob(s string) string {
+	var sb strings.Builder
+	for _, ch := range s {
+		switch ch {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package cache_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/redis/redistest"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// waitKey 缓存是异步写入的, 等待 key 出现在 redis 中
+func waitKey(t *testing.T, s *redistest.Server, key string) {
+	deadline := time.Now().Add(time.Second * 3)
+	for time.Now().Before(deadline) {
+		if slices.Contains(s.Keys(), key) {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("等待 key 超时: %s, 当前 key: %v", key, s.Keys())
+}
+
+func TestRedisBackend(t *testing.T) {
+	s, err := redistest.NewServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	initConfig(t, fmt.Sprintf(`  backend: redis
+  redis:
+    addr: %s
+    prefix: "test:"
+  routes:
+    - pattern: (?i)^/shared
+`, s.Addr()))
+	if err := cache.InitBackend(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		config.C().Cache.Backend = config.CacheBackendMemory
+		cache.InitBackend()
+	}()
+
+	f := newFixture("Shared")
+	f.handle("/shared", func(c *gin.Context) {
+		c.Header(cache.HeaderKeySpaceKey, "item_1")
+		c.String(http.StatusOK, "shared body")
+	})
+	do := func() *httptest.ResponseRecorder { return f.get("/shared") }
+
+	if w := do(); w.Body.String() != "shared body" {
+		t.Fatalf("响应异常: %s", w.Body.String())
+	}
+	waitKey(t, s, "test:space:Shared:item_1")
+
+	var entryKey string
+	for _, key := range s.Keys() {
+		if strings.HasPrefix(key, "test:entry:") {
+			entryKey = key
+		}
+	}
+	if ttl := s.TTL(entryKey); ttl <= 0 {
+		t.Errorf("缓存未设置过期时间: %s", entryKey)
+	}
+
+	// 缓存空间中的缓存可以被其他请求读取
+	rc, ok := cache.GetSpaceCache("Shared", "item_1")
+	if !ok || string(rc.BodyBytes()) != "shared body" {
+		t.Fatal("读取 redis 缓存空间失败")
+	}
+
+	// 修改缓存后写回 redis
+	rc.Update(0, []byte("updated body"), nil)
+	if rc, ok := cache.GetSpaceCache("Shared", "item_1"); !ok || string(rc.BodyBytes()) != "updated body" {
+		t.Fatal("修改 redis 缓存失败")
+	}
+
+	// 第二次请求命中缓存, 不再调用处理器
+	if w := do(); w.Body.String() != "updated body" || f.calls.Load() != 1 {
+		t.Fatalf("期望命中缓存, 响应: %s, 处理器调用次数: %d", w.Body.String(), f.calls.Load())
+	}
+
+	stats := cache.GetStats()
+	if stats.Backend != config.CacheBackendRedis || stats.Count != 1 || stats.Spaces["Shared"] != 1 {
+		t.Errorf("缓存统计异常: %+v", stats)
+	}
+
+	if n := cache.PurgeSpace("Shared", ""); n != 1 {
+		t.Fatalf("期望清理 1 条缓存, 实际: %d", n)
+	}
+	if keys := s.Keys(); len(keys) != 0 {
+		t.Errorf("清理后 redis 中仍有残留: %v", keys)
+	}
+}
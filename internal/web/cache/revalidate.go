@@ -0,0 +1,141 @@
+// 过期缓存后台刷新功能, 缓存过期后的一段时间内仍然使用旧的缓存响应客户端,
+// 同时在后台重放原始请求以刷新缓存
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+const (
+
+	// HeaderKeyStale 缓存过期后仍可继续使用的时长 (毫秒)
+	//
+	// 在此期间命中缓存时, 会使用旧的缓存响应客户端, 并在后台刷新缓存
+	HeaderKeyStale = "Stale"
+
+	// HeaderKeyRevalidate 后台刷新缓存时携带的请求头, 用于跳过缓存
+	HeaderKeyRevalidate = "X-Ge2o-Revalidate"
+
+	// RevalidateTimeout 后台刷新缓存的超时时间
+	RevalidateTimeout = time.Minute
+)
+
+// RevalidateInterval 同一个缓存两次后台刷新的最小时间间隔
+var RevalidateInterval = time.Second * 30
+
+// revalidateToken 后台刷新缓存的请求凭证, 避免外部请求通过请求头跳过缓存
+var revalidateToken = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+// refreshing 正在刷新或者 RevalidateInterval 内刷新过的缓存 cacheKey
+//
+// 从 redis 等存储后端读取的缓存每次都是新的副本, 因此按照 cacheKey 记录刷新状态
+var refreshing sync.Map
+
+// StaleDuration 将一个标准的时间转换成适用于 "Stale" 响应头的字符串
+func StaleDuration(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
+// isRevalidateRequest 判断请求是否是后台刷新缓存的请求
+//
+// 判断完成后会移除请求头, 避免影响 cache key 计算
+func isRevalidateRequest(r *http.Request) bool {
+	token := r.Header.Get(HeaderKeyRevalidate)
+	r.Header.Del(HeaderKeyRevalidate)
+	return token != "" && token == revalidateToken
+}
+
+// revalidate 在后台重放原始请求, 刷新过期的缓存
+//
+// 重放时使用触发刷新的请求 r 中的客户端凭证,
+// 从磁盘或 redis 中加载的缓存不包含凭证, 需要依赖 r 重新注入;
+// 距离上次刷新不足 RevalidateInterval 时不做处理
+func revalidate(rc *respCache, r *http.Request) {
+	if _, loaded := refreshing.LoadOrStore(rc.cacheKey, struct{}{}); loaded {
+		return
+	}
+	time.AfterFunc(RevalidateInterval, func() { refreshing.Delete(rc.cacheKey) })
+
+	header := rc.req.header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	uri := injectCredentials(header, rc.uri, r)
+
+	go func() {
+		header.Set(HeaderKeyRevalidate, revalidateToken)
+		header.Set(constant.HeaderInternalToken, config.InternalToken())
+
+		ctx, cancel := context.WithTimeout(context.Background(), RevalidateTimeout)
+		defer cancel()
+		resp, err := https.Request(rc.req.method, config.ServerInternalRequestHost()+uri).
+			Context(ctx).
+			Header(header).
+			Body(io.NopCloser(bytes.NewReader(rc.req.body))).
+			DoSingle()
+		if err != nil {
+			logs.Warn("后台刷新缓存失败: %v, uri: %s", err, rc.uri)
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if https.IsErrorStatus(resp.StatusCode) {
+			logs.Warn("后台刷新缓存失败, code: %d, uri: %s", resp.StatusCode, rc.uri)
+			return
+		}
+		revalidations.Add(1)
+	}()
+}
+
+// injectCredentials 将请求 r 中的客户端凭证注入到重放请求的请求头 header 中,
+// 返回注入凭证后的请求地址
+func injectCredentials(header http.Header, uri string, r *http.Request) string {
+	for _, key := range credentialHeaders {
+		if v := r.Header.Values(key); len(v) > 0 {
+			header[http.CanonicalHeaderKey(key)] = slices.Clone(v)
+		}
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	rq := make(url.Values)
+	for key, v := range r.URL.Query() {
+		if isCredentialParam(key) {
+			rq[key] = v
+		}
+	}
+	if len(rq) == 0 {
+		return uri
+	}
+	q := u.Query()
+	for key := range q {
+		if isCredentialParam(key) {
+			q.Del(key)
+		}
+	}
+	maps.Copy(q, rq)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
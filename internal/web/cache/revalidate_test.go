@@ -0,0 +1,224 @@
+package cache_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	defer func(interval time.Duration) { cache.RevalidateInterval = interval }(cache.RevalidateInterval)
+	cache.RevalidateInterval = time.Second
+
+	const expired = time.Millisecond * 300
+	// 第一次后台刷新在旧的缓存响应完所有请求之后才返回
+	release := make(chan struct{})
+	unblock := sync.OnceFunc(func() { close(release) })
+	defer unblock()
+	f := newFixture("Swr")
+	f.handle("/swr", func(c *gin.Context) {
+		n := f.calls.Load()
+		if n == 2 {
+			<-release
+		}
+		c.Header(cache.HeaderKeyExpired, cache.Duration(expired))
+		c.Header(cache.HeaderKeyStale, cache.StaleDuration(time.Minute))
+		c.Header(cache.HeaderKeySpaceKey, "swr")
+		c.String(http.StatusOK, "v%d", n)
+	})
+
+	// 后台刷新需要通过服务自身的监听地址重放请求
+	server := httptest.NewServer(f)
+	defer server.Close()
+	initConfig(t, fmt.Sprintf(`  routes:
+    - pattern: (?i)^/swr
+server:
+  listen:
+    http:
+      - %s
+`, strings.TrimPrefix(server.URL, "http://")))
+	defer cache.PurgeSpace("Swr", "")
+
+	uri := server.URL + "/swr?nonce=" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	get := func(header http.Header) string {
+		req, _ := http.NewRequest(http.MethodGet, uri, nil)
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	// waitRefreshed 等待后台刷新后的缓存写入
+	waitRefreshed := func(want string) {
+		deadline := time.Now().Add(time.Second * 3)
+		for time.Now().Before(deadline) {
+			if rc, ok := cache.GetSpaceCache("Swr", "swr"); ok && string(rc.BodyBytes()) == want {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Fatalf("等待缓存刷新为 %s 超时", want)
+	}
+
+	if body := get(nil); body != "v1" {
+		t.Fatalf("首次请求响应异常: %s", body)
+	}
+	cache.WaitingForHandleChan()
+
+	// 外部请求伪造刷新请求头, 不能跳过缓存
+	if body := get(http.Header{cache.HeaderKeyRevalidate: {"forged"}}); body != "v1" || f.calls.Load() != 1 {
+		t.Fatalf("伪造的刷新请求头跳过了缓存, 响应: %s, 处理器调用次数: %d", body, f.calls.Load())
+	}
+
+	// 缓存过期后, 宽限期内使用旧的缓存响应, 并发请求只触发一次后台刷新
+	time.Sleep(expired + time.Millisecond*50)
+	revalidatedAt := time.Now()
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if body := get(nil); body != "v1" {
+				t.Errorf("期望使用旧的缓存响应, 实际: %s", body)
+			}
+		}()
+	}
+	wg.Wait()
+	unblock()
+	waitRefreshed("v2")
+	if f.calls.Load() != 2 {
+		t.Fatalf("期望只进行一次后台刷新, 处理器调用次数: %d", f.calls.Load())
+	}
+
+	// 刷新后的缓存再次过期, 距离上次刷新不足 RevalidateInterval 时不再刷新
+	time.Sleep(expired + time.Millisecond*50)
+	if time.Since(revalidatedAt) >= cache.RevalidateInterval {
+		t.Skip("运行过慢, 跳过刷新间隔校验")
+	}
+	if body := get(nil); body != "v2" {
+		t.Fatalf("期望使用旧的缓存响应, 实际: %s", body)
+	}
+	time.Sleep(time.Millisecond * 100)
+	if f.calls.Load() != 2 {
+		t.Fatalf("刷新间隔内不应再次刷新, 处理器调用次数: %d", f.calls.Load())
+	}
+
+	// 超过刷新间隔后再次触发刷新
+	time.Sleep(time.Until(revalidatedAt.Add(cache.RevalidateInterval + time.Millisecond*50)))
+	if body := get(nil); body != "v2" {
+		t.Fatalf("期望使用旧的缓存响应, 实际: %s", body)
+	}
+	waitRefreshed("v3")
+	if f.calls.Load() != 3 {
+		t.Fatalf("期望再次进行后台刷新, 处理器调用次数: %d", f.calls.Load())
+	}
+}
+
+func TestRevalidateCredentials(t *testing.T) {
+	const expired = time.Millisecond * 200
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+	f := newFixture("Cred")
+	f.handle("/cred", func(c *gin.Context) {
+		mu.Lock()
+		seen = append(seen, c.GetHeader("X-Emby-Token")+"|"+c.GetHeader("Cookie")+"|"+c.Query("api_key"))
+		mu.Unlock()
+		c.Header(cache.HeaderKeyExpired, cache.Duration(expired))
+		c.Header(cache.HeaderKeyStale, cache.StaleDuration(time.Minute))
+		c.Header(cache.HeaderKeySpaceKey, "cred")
+		c.String(http.StatusOK, "v%d", f.calls.Load())
+	})
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	dir := t.TempDir()
+	initConfig(t, fmt.Sprintf(`  disk:
+    enable: true
+    dir: %s
+  routes:
+    - pattern: (?i)^/cred
+server:
+  listen:
+    http:
+      - %s
+`, dir, strings.TrimPrefix(server.URL, "http://")))
+	resetStore(t)
+	if err := cache.InitDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 请求地址需要拼接 nonce, 避免命中之前用例的刷新间隔
+	uri := server.URL + "/cred?api_key=secret-key&nonce=" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	get := func() {
+		req, _ := http.NewRequest(http.MethodGet, uri, nil)
+		req.Header.Set("X-Emby-Token", "secret-token")
+		req.Header.Set("Cookie", "session=secret-cookie")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	get()
+	cache.WaitingForHandleChan()
+
+	// 持久化的缓存不包含客户端凭证, 并且只允许当前用户读写
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+cache.DiskFileExt))
+	if len(files) != 1 {
+		t.Fatalf("期望写入 1 个缓存文件, 实际: %d", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatal("缓存文件中包含客户端凭证")
+	}
+	if stat, err := os.Stat(files[0]); err != nil || stat.Mode().Perm() != 0600 {
+		t.Fatalf("缓存文件权限异常: %v, err: %v", stat.Mode().Perm(), err)
+	}
+
+	// 从磁盘加载的缓存过期后, 使用触发刷新的请求中的凭证重放请求
+	cache.ResetStore()
+	if err := cache.InitDisk(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(expired + time.Millisecond*50)
+	get()
+	deadline := time.Now().Add(time.Second * 3)
+	for time.Now().Before(deadline) {
+		if rc, ok := cache.GetSpaceCache("Cred", "cred"); ok && string(rc.BodyBytes()) == "v2" {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	cache.WaitingForHandleChan()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "secret-token|session=secret-cookie|secret-key"
+	if len(seen) != 2 || seen[1] != want {
+		t.Fatalf("后台刷新请求的凭证异常: %v", seen)
+	}
+}
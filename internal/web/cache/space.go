@@ -27,8 +27,7 @@ func GetSpaceCache(space, spaceKey string) (RespCache, bool) {
 	if strs.AnyEmpty(space, spaceKey) {
 		return nil, false
 	}
-	s := getSpace(space)
-	rc, ok := getSpaceCache(s, spaceKey)
+	rc, ok := storage.getSpace(space, spaceKey)
 	if !ok {
 		return nil, false
 	}
@@ -43,11 +42,14 @@ func putSpaceCache(space, spaceKey string, cache *respCache) {
 	getSpace(space).Store(spaceKey, cache)
 }
 
-func delSpaceCache(space, spaceKey string) {
+// delSpaceCache 删除缓存空间中的缓存
+//
+// 只有当缓存空间中存放的仍是 cache 对象时才会删除, 避免误删新的缓存
+func delSpaceCache(space, spaceKey string, cache *respCache) {
 	if strs.AnyEmpty(space, spaceKey) {
 		return
 	}
-	getSpace(space).Delete(spaceKey)
+	getSpace(space).CompareAndDelete(spaceKey, cache)
 }
 
 // getSpace 获取缓存空间
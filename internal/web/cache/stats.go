@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"regexp"
+	"sync/atomic"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+var (
+	// hits 命中缓存的请求数
+	hits atomic.Int64
+
+	// misses 未命中缓存的请求数, 不包含不走缓存的请求
+	misses atomic.Int64
+
+	// coalesced 等待相同请求的响应后直接复用的请求数
+	coalesced atomic.Int64
+
+	// stales 使用已过期的缓存响应的请求数
+	stales atomic.Int64
+
+	// revalidations 后台刷新成功的缓存数
+	revalidations atomic.Int64
+
+	// evictions 因过期或超出容量被淘汰的缓存数
+	evictions atomic.Int64
+)
+
+func init() {
+	metrics.NewCounterFunc("ge2o_cache_hits_total", "命中缓存的请求数", func() float64 { return float64(hits.Load()) })
+	metrics.NewCounterFunc("ge2o_cache_misses_total", "未命中缓存的请求数", func() float64 { return float64(misses.Load()) })
+	metrics.NewCounterFunc("ge2o_cache_coalesced_total", "等待相同请求的响应后直接复用的请求数", func() float64 { return float64(coalesced.Load()) })
+	metrics.NewCounterFunc("ge2o_cache_stale_hits_total", "使用已过期的缓存响应的请求数", func() float64 { return float64(stales.Load()) })
+	metrics.NewCounterFunc("ge2o_cache_revalidations_total", "后台刷新成功的缓存数", func() float64 { return float64(revalidations.Load()) })
+	metrics.NewCounterFunc("ge2o_cache_evictions_total", "因过期或超出容量被淘汰的缓存数", func() float64 { return float64(evictions.Load()) })
+	metrics.NewGaugeFunc("ge2o_cache_size_bytes", "当前缓存的响应体总大小 (压缩后)", func() float64 {
+		_, size := storage.usage()
+		return float64(size)
+	})
+	metrics.NewGaugeFunc("ge2o_cache_entries", "当前缓存的条目数", func() float64 {
+		cnt, _ := storage.usage()
+		return float64(cnt)
+	})
+	metrics.NewGaugeFunc("ge2o_cache_disk_size_bytes", "当前磁盘缓存的文件总大小", func() float64 {
+		_, size := diskUsage()
+		return float64(size)
+	})
+}
+
+// Stats 缓存统计信息
+type Stats struct {
+	Enable    bool           `json:"enable"`    // 缓存是否启用
+	Backend   string         `json:"backend"`   // 缓存存储后端
+	Count     int            `json:"count"`     // 缓存条目数
+	Size      int64          `json:"size"`      // 缓存响应体总大小 (压缩后, Byte)
+	MaxNum    int            `json:"max_num"`   // 最大缓存条目数
+	MaxSize   int64          `json:"max_size"`  // 最大缓存大小 (Byte)
+	Hits      int64          `json:"hits"`      // 命中数
+	Misses    int64          `json:"misses"`    // 未命中数
+	Coalesced int64          `json:"coalesced"` // 合并请求数
+	Evicted   int64          `json:"evicted"`   // 淘汰数
+	Spaces    map[string]int `json:"spaces"`    // 缓存空间名称 => 条目数
+
+	DiskCount int   `json:"disk_count"` // 磁盘缓存文件数
+	DiskSize  int64 `json:"disk_size"`  // 磁盘缓存文件总大小 (Byte)
+}
+
+// GetStats 获取当前的缓存统计信息
+func GetStats() Stats {
+	s := Stats{
+		Enable:    config.C().Cache.Enable,
+		Backend:   storage.name(),
+		MaxNum:    config.C().Cache.MaxNum,
+		MaxSize:   config.C().Cache.MaxSizeBytes(),
+		Hits:      hits.Load(),
+		Misses:    misses.Load(),
+		Coalesced: coalesced.Load(),
+		Evicted:   evictions.Load(),
+	}
+	s.Count, s.Size = storage.usage()
+	s.DiskCount, s.DiskSize = diskUsage()
+	s.Spaces = storage.spaces()
+	return s
+}
+
+// PurgeKey 清理指定 cacheKey 的缓存, 返回清理的条目数
+func PurgeKey(cacheKey string) int {
+	rc, ok := storage.get(cacheKey)
+	if !ok || !storage.remove(rc) {
+		return 0
+	}
+	return 1
+}
+
+// PurgeSpace 清理缓存空间中的缓存, 返回清理的条目数
+//
+// spaceKey 为空时, 清理整个缓存空间
+func PurgeSpace(space, spaceKey string) int {
+	return PurgeSpaceFunc(space, func(key string, _ RespCache) bool {
+		return spaceKey == "" || key == spaceKey
+	})
+}
+
+// PurgeSpaceFunc 清理缓存空间中满足条件的缓存, 返回清理的条目数
+func PurgeSpaceFunc(space string, match func(spaceKey string, rc RespCache) bool) int {
+	if strs.AnyEmpty(space) || match == nil {
+		return 0
+	}
+	cnt := 0
+	storage.rangeSpace(space, func(spaceKey string, rc *respCache) bool {
+		if match(spaceKey, rc) && storage.remove(rc) {
+			cnt++
+		}
+		return true
+	})
+	return cnt
+}
+
+// PurgeRegex 清理请求 uri 匹配正则表达式的缓存, 返回清理的条目数
+func PurgeRegex(reg *regexp.Regexp) int {
+	if reg == nil {
+		return 0
+	}
+	cnt := 0
+	storage.rangeAll(func(rc *respCache) bool {
+		if reg.MatchString(rc.uri) && storage.remove(rc) {
+			cnt++
+		}
+		return true
+	})
+	return cnt
+}
@@ -14,28 +14,62 @@ import (
 type respCacheWriter struct {
 	gin.ResponseWriter               // gin 原始的响应器
 	body               *bytes.Buffer // gin 回写响应时, 同步缓存
+
+	// onWrite 首次写出响应体时回调, 此时响应头已经确定
+	onWrite func(header http.Header)
+	once    sync.Once
 }
 
 func (rcw *respCacheWriter) Write(b []byte) (int, error) {
+	rcw.notifyWrite()
 	rcw.body.Write(b)
 	return rcw.ResponseWriter.Write(b)
 }
 
+// WriteString gin 写出字符串响应时不会经过 Write 方法, 需要单独缓存
+func (rcw *respCacheWriter) WriteString(s string) (int, error) {
+	rcw.notifyWrite()
+	rcw.body.WriteString(s)
+	return rcw.ResponseWriter.WriteString(s)
+}
+
+// notifyWrite 触发 onWrite 回调
+func (rcw *respCacheWriter) notifyWrite() {
+	if rcw.onWrite == nil {
+		return
+	}
+	rcw.once.Do(func() { rcw.onWrite(rcw.ResponseWriter.Header()) })
+}
+
 // respCache 存放请求的响应信息
 type respCache struct {
 
 	// code 响应码
 	code int
 
-	// body 响应体
+	// body 响应体, gzip 为 true 时存放的是压缩后的数据
 	body []byte
 
+	// gzip 响应体是否经过 gzip 压缩
+	gzip bool
+
 	// cacheKey 缓存 key
 	cacheKey string
 
+	// uri 原始请求的 uri, 用于按照正则表达式清理缓存
+	uri string
+
 	// expired 缓存过期时间戳 UnixMilli
 	expired int64
 
+	// stale 缓存过期后仍可继续使用的截止时间戳 UnixMilli, 为 0 时过期后立即失效
+	//
+	// 在此期间命中缓存时, 会在后台重新请求以刷新缓存
+	stale int64
+
+	// req 原始请求信息, 用于在后台刷新缓存
+	req replayRequest
+
 	// header 响应头信息
 	header respHeader
 
@@ -43,9 +77,17 @@ type respCache struct {
 	mu sync.RWMutex
 }
 
+// replayRequest 记录原始请求的信息, 用于重放请求
+type replayRequest struct {
+	method string      // 请求方法
+	header http.Header // 请求头
+	body   []byte      // 请求体
+}
+
 // respHeader 记录特定请求的缓存参数
 type respHeader struct {
 	expired  string      // 过期时间
+	stale    string      // 过期后仍可继续使用的时长
 	space    string      // 缓存空间名称
 	spaceKey string      // 缓存空间 key
 	header   http.Header // 原始请求的克隆请求头
@@ -60,8 +102,6 @@ func (c *respCache) Code() int {
 
 // Body 克隆一个响应体, 转换为缓冲区
 func (c *respCache) Body() *bytes.Buffer {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
 	return bytes.NewBuffer(c.BodyBytes())
 }
 
@@ -69,6 +109,9 @@ func (c *respCache) Body() *bytes.Buffer {
 func (c *respCache) BodyBytes() []byte {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	if c.gzip {
+		return c.plainBody()
+	}
 	return append([]byte(nil), c.body...)
 }
 
@@ -76,7 +119,7 @@ func (c *respCache) BodyBytes() []byte {
 func (c *respCache) JsonBody() (*jsons.Item, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return jsons.New(string(c.body))
+	return jsons.New(string(c.plainBody()))
 }
 
 // Header 获取响应头属性
@@ -120,6 +163,7 @@ func (c *respCache) Update(code int, body []byte, header http.Header) {
 	if code == 0 && body == nil && header == nil {
 		return
 	}
+	defer storage.update(c)
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -127,12 +171,17 @@ func (c *respCache) Update(code int, body []byte, header http.Header) {
 		c.code = code
 	}
 
+	if header != nil {
+		c.header.header = header.Clone()
+	}
+
 	if body != nil {
 		// 新建一个底层数组来存放响应体数据
-		c.body = append(([]byte)(nil), body...)
+		c.body, c.gzip = compressBody(append(([]byte)(nil), body...), c.header.header)
 	}
+}
 
-	if header != nil {
-		c.header.header = header.Clone()
-	}
+// deadline 缓存彻底失效的时间戳 UnixMilli
+func (c *respCache) deadline() int64 {
+	return max(c.expired, c.stale)
 }
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// CertCheckInterval 检查证书文件是否发生变更的最小时间间隔
+var CertCheckInterval = time.Second * 10
+
+// certs 全局证书持有者
+var certs = new(certHolder)
+
+// certHolder 缓存当前使用的 TLS 证书
+//
+// 配置重载后会清空缓存, 下一次握手时按最新的配置路径重新加载证书;
+// 证书文件被外部工具更新后, 也会在下一次握手时自动重新加载
+type certHolder struct {
+	mu sync.Mutex
+
+	// crtPath, keyPath 已加载证书对应的文件路径
+	crtPath, keyPath string
+
+	// crtMod, keyMod 已加载证书对应文件的修改时间
+	crtMod, keyMod time.Time
+
+	// checkedAt 最近一次检查文件变更的时间
+	checkedAt time.Time
+
+	// cert 已加载的证书
+	cert *tls.Certificate
+}
+
+// get 获取证书, 用于 tls.Config.GetCertificate
+func (ch *certHolder) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	ssl := config.C().Ssl
+	crtPath, keyPath := ssl.CrtPath(), ssl.KeyPath()
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	samePath := ch.cert != nil && ch.crtPath == crtPath && ch.keyPath == keyPath
+	if samePath && time.Since(ch.checkedAt) < CertCheckInterval {
+		return ch.cert, nil
+	}
+	ch.checkedAt = time.Now()
+
+	crtMod, keyMod := modTime(crtPath), modTime(keyPath)
+	if samePath && crtMod.Equal(ch.crtMod) && keyMod.Equal(ch.keyMod) {
+		return ch.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(crtPath, keyPath)
+	if err != nil {
+		if samePath {
+			// 证书可能正在被更新, 先继续使用旧证书, 下次检查时再重试
+			logs.Warn("重新加载 ssl 证书失败, 继续使用旧证书: %v", err)
+			return ch.cert, nil
+		}
+		return nil, fmt.Errorf("加载 ssl 证书失败: %v", err)
+	}
+	if samePath {
+		logs.Success("检测到 ssl 证书文件变更, 已重新加载")
+	}
+	ch.crtPath, ch.keyPath, ch.cert = crtPath, keyPath, &cert
+	ch.crtMod, ch.keyMod = crtMod, keyMod
+	return ch.cert, nil
+}
+
+// reset 清空证书缓存
+func (ch *certHolder) reset() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.cert = nil
+}
+
+// modTime 获取文件的修改时间, 获取失败时返回零值
+func modTime(path string) time.Time {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return stat.ModTime()
+}
@@ -0,0 +1,108 @@
+package web_test
+
+import (
+	"crypto/tls"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web"
+)
+
+func TestCertReload(t *testing.T) {
+	defer func(interval time.Duration) { web.CertCheckInterval = interval }(web.CertCheckInterval)
+	web.CertCheckInterval = time.Millisecond * 300
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := `emby:
+  host: http://127.0.0.1:8096
+openlist:
+  host: http://127.0.0.1:5244
+  token: stub-token
+ssl:
+  enable: true
+  self-signed: true
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	ssl := config.C().Ssl
+	if err := ssl.EnsureCert(); err != nil {
+		t.Fatal(err)
+	}
+	web.ResetCerts()
+	t.Cleanup(web.ResetCerts)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler:   http.NotFoundHandler(),
+		TLSConfig: &tls.Config{GetCertificate: web.GetCertificate},
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	// served 返回服务端当前使用的证书序列号
+	served := func() *big.Int {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber
+	}
+	// touch 将证书文件的修改时间往后推, 保证能检测到变更
+	touch := func(offset time.Duration) {
+		mod := time.Now().Add(offset)
+		for _, path := range []string{ssl.CrtPath(), ssl.KeyPath()} {
+			if err := os.Chtimes(path, mod, mod); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	start := time.Now()
+	first := served()
+
+	// 使用新的证书覆盖原来的证书文件
+	for _, path := range []string{ssl.CrtPath(), ssl.KeyPath()} {
+		if err := os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ssl.EnsureCert(); err != nil {
+		t.Fatal(err)
+	}
+	touch(time.Minute)
+
+	// 检查间隔内继续使用已加载的证书
+	if got := served(); time.Since(start) < web.CertCheckInterval && got.Cmp(first) != 0 {
+		t.Fatal("检查间隔内不应重新加载证书")
+	}
+
+	time.Sleep(web.CertCheckInterval + time.Millisecond*50)
+	second := served()
+	if second.Cmp(first) == 0 {
+		t.Fatal("证书文件变更后没有重新加载")
+	}
+
+	// 证书文件损坏时继续使用旧证书
+	if err := os.WriteFile(ssl.CrtPath(), []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(time.Minute * 2)
+	time.Sleep(web.CertCheckInterval + time.Millisecond*50)
+	if got := served(); got.Cmp(second) != 0 {
+		t.Fatal("证书文件损坏时期望继续使用旧证书")
+	}
+}
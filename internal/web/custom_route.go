@@ -0,0 +1,82 @@
+package web
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// customRules 将配置中的自定义路由转换为路由规则定义
+//
+// 按照配置的位置分别返回在内置规则之前以及之后匹配的规则;
+// 内置处理器名称已经在配置校验时检查过, 找不到时说明 constant.BuiltinRoutes 与 builtinRules 不一致
+func customRules(routes config.Routes, builtins []ruleDef) (before, after []ruleDef) {
+	handlers := make(map[string]func(*gin.Context), len(builtins))
+	for _, def := range builtins {
+		handlers[def.name] = def.handler
+	}
+
+	for _, r := range routes {
+		var handler func(*gin.Context)
+		switch r.Action {
+		case config.RouteActionOrigin:
+			handler = emby.ProxyOrigin
+		case config.RouteActionReject:
+			handler = rejectHandler(r.Status)
+		case config.RouteActionRedirect:
+			handler = redirectHandler(r.Pattern, r.Target, r.Status)
+		case config.RouteActionBuiltin:
+			h, ok := handlers[r.Handler]
+			if !ok {
+				logs.Error("自定义路由 [%s] 引用了不存在的内置处理器: %s, 已跳过", r.Name, r.Handler)
+				continue
+			}
+			handler = h
+		}
+
+		def := ruleDef{
+			name:    r.Name,
+			pattern: r.Pattern,
+			handler: handler,
+			match:   func(c *gin.Context) bool { return r.Match(c.Request.Method, c.Request.Header) },
+		}
+		if r.Position == config.RoutePositionAfter {
+			after = append(after, def)
+		} else {
+			before = append(before, def)
+		}
+	}
+	return
+}
+
+// rejectHandler 使用指定的状态码拒绝请求
+func rejectHandler(status int) func(*gin.Context) {
+	return func(c *gin.Context) {
+		c.Header(cache.HeaderKeyExpired, "-1")
+		c.String(status, http.StatusText(status))
+	}
+}
+
+// redirectHandler 按照模板生成地址并重定向
+//
+// 模板中的 ${uri} 会被替换为原始请求 uri, $1, ${name} 引用 pattern 的捕获组
+func redirectHandler(pattern, target string, status int) func(*gin.Context) {
+	reg := regexp.MustCompile(pattern)
+	return func(c *gin.Context) {
+		uri := c.Request.RequestURI
+		// 转义 uri 中的 $ 符号, 避免被当作捕获组引用
+		tmpl := strings.ReplaceAll(target, "${uri}", strings.ReplaceAll(uri, "$", "$$"))
+
+		dst := reg.ExpandString(nil, tmpl, uri, reg.FindStringSubmatchIndex(uri))
+
+		c.Header(cache.HeaderKeyExpired, "-1")
+		c.Redirect(status, string(dst))
+	}
+}
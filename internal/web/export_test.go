@@ -0,0 +1,27 @@
+package web
+
+import "github.com/gin-gonic/gin"
+
+// 导出给测试使用
+var (
+	Protocols       = protocols
+	StripHopHeaders = stripHopHeaders
+	GetCertificate  = certs.get
+	ResetCerts      = certs.reset
+)
+
+// BuiltinRuleNames 按顺序返回内置路由规则的名称
+func BuiltinRuleNames() []string {
+	defs := builtinRules()
+	names := make([]string, 0, len(defs))
+	for _, def := range defs {
+		names = append(names, def.name)
+	}
+	return names
+}
+
+// InitRouter 初始化路由规则以及路由引擎
+func InitRouter(r *gin.Engine) {
+	initRulePatterns()
+	initRouter(r)
+}
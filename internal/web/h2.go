@@ -0,0 +1,93 @@
+package web
+
+import (
+	"net/http"
+)
+
+// hopHeaders 逐跳响应头, HTTP/2 中不允许出现 (RFC 9113 8.2.2)
+//
+// 代理回源时会原样复制上游的响应头, 需要在写出响应前移除, 否则客户端会判定为协议错误
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// protocols 根据配置生成服务支持的协议
+//
+// tls 标记当前服务是否为 https 服务
+func protocols(tls, http2, h2c bool) *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	if tls {
+		p.SetHTTP2(http2)
+	} else {
+		p.SetUnencryptedHTTP2(h2c)
+	}
+	return p
+}
+
+// stripHopHeaders 对 HTTP/2 请求移除响应中的逐跳响应头
+func stripHopHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 2 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&h2Writer{ResponseWriter: w}, r)
+	})
+}
+
+// h2Writer 在写出响应头之前移除逐跳响应头
+type h2Writer struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// strip 移除逐跳响应头
+func (w *h2Writer) strip() {
+	h := w.Header()
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+}
+
+func (w *h2Writer) WriteHeader(code int) {
+	w.strip()
+	if code >= http.StatusOK {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *h2Writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush 流式响应需要及时刷新缓冲区
+func (w *h2Writer) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify gin 依赖该方法感知客户端断开
+func (w *h2Writer) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Unwrap 支持 http.ResponseController 访问原始的响应器
+func (w *h2Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,224 @@
+package web_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newH2Handler 初始化经过缓存中间件的测试路由
+//
+// flushed 在客户端收到流式响应的第一段数据后关闭, 响应才会继续写出
+func newH2Handler(t *testing.T, calls *atomic.Int32, flushed <-chan struct{}) http.Handler {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := `emby:
+  host: http://127.0.0.1:8096
+openlist:
+  host: http://127.0.0.1:5244
+  token: stub-token
+cache:
+  enable: true
+  routes:
+    - pattern: (?i)^/cached
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cache.PurgeRegex(regexp.MustCompile(`^/cached`)) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(cache.CacheableRouteMarker(), cache.RequestCacher())
+	// 回源代理时会原样复制上游的逐跳响应头
+	r.GET("/hop", func(c *gin.Context) {
+		c.Header("Connection", "keep-alive")
+		c.Header("Keep-Alive", "timeout=5")
+		c.Header("Proxy-Connection", "keep-alive")
+		c.Header("Upgrade", "h2c")
+		c.String(http.StatusOK, "hop")
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		c.String(http.StatusOK, "first\n")
+		c.Writer.Flush()
+		select {
+		case <-flushed:
+		case <-time.After(time.Second * 3):
+		}
+		c.String(http.StatusOK, "second\n")
+	})
+	r.GET("/cached/body", func(c *gin.Context) {
+		calls.Add(1)
+		c.Header("Connection", "close")
+		c.String(http.StatusOK, "cached body")
+	})
+	r.GET("/cached/redirect", func(c *gin.Context) {
+		calls.Add(1)
+		c.Redirect(http.StatusTemporaryRedirect, "http://example.com/direct")
+	})
+	// websocket 等协议升级只会发生在 HTTP/1.1 连接上
+	r.GET("/upgrade", func(c *gin.Context) {
+		conn, buf, err := http.NewResponseController(c.Writer).Hijack()
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\nupgraded")
+		buf.Flush()
+	})
+	return web.StripHopHeaders(r)
+}
+
+// testH2 校验 HTTP/2 连接上的响应
+func testH2(t *testing.T, client *http.Client, baseUrl string, calls *atomic.Int32, flushed chan struct{}) {
+	get := func(uri string) *http.Response {
+		resp, err := client.Get(baseUrl + uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.ProtoMajor != 2 {
+			t.Fatalf("期望使用 HTTP/2, 实际: %s", resp.Proto)
+		}
+		return resp
+	}
+
+	t.Run("hop-by-hop headers stripped", func(t *testing.T) {
+		resp := get("/hop")
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "hop" {
+			t.Fatalf("响应体异常: %s", body)
+		}
+		for _, h := range []string{"Connection", "Keep-Alive", "Proxy-Connection", "Upgrade"} {
+			if v := resp.Header.Get(h); v != "" {
+				t.Errorf("逐跳响应头 %s 未被移除: %s", h, v)
+			}
+		}
+	})
+
+	t.Run("flush reaches client", func(t *testing.T) {
+		resp := get("/stream")
+		defer resp.Body.Close()
+		br := bufio.NewReader(resp.Body)
+		line, err := br.ReadString('\n')
+		if err != nil || line != "first\n" {
+			t.Fatalf("未收到刷新的数据: %q, err: %v", line, err)
+		}
+		close(flushed)
+		if rest, _ := io.ReadAll(br); string(rest) != "second\n" {
+			t.Fatalf("响应体异常: %q", rest)
+		}
+	})
+
+	t.Run("cached replay", func(t *testing.T) {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		defer func() { client.CheckRedirect = nil }()
+		for _, tc := range []struct{ uri, body, location string }{
+			{uri: "/cached/body", body: "cached body"},
+			{uri: "/cached/redirect", location: "http://example.com/direct"},
+		} {
+			before := calls.Load()
+			for i := range 2 {
+				resp := get(tc.uri)
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if tc.body != "" && string(body) != tc.body {
+					t.Errorf("%s 第 %d 次请求响应体异常: %s", tc.uri, i+1, body)
+				}
+				if loc := resp.Header.Get("Location"); loc != tc.location {
+					t.Errorf("%s 第 %d 次请求 Location 异常: %s", tc.uri, i+1, loc)
+				}
+				if resp.Header.Get("Connection") != "" {
+					t.Errorf("%s 第 %d 次请求包含逐跳响应头", tc.uri, i+1)
+				}
+				cache.WaitingForHandleChan()
+			}
+			if got := calls.Load() - before; got != 1 {
+				t.Errorf("%s 期望命中缓存, 处理器调用次数: %d", tc.uri, got)
+			}
+		}
+	})
+}
+
+func TestHTTP2(t *testing.T) {
+	var calls atomic.Int32
+	flushed := make(chan struct{})
+	srv := httptest.NewUnstartedServer(newH2Handler(t, &calls, flushed))
+	srv.EnableHTTP2 = true
+	srv.Config.Protocols = web.Protocols(true, true, false)
+	srv.StartTLS()
+	defer srv.Close()
+
+	testH2(t, srv.Client(), srv.URL, &calls, flushed)
+}
+
+func TestH2C(t *testing.T) {
+	var calls atomic.Int32
+	flushed := make(chan struct{})
+	srv := httptest.NewUnstartedServer(newH2Handler(t, &calls, flushed))
+	srv.Config.Protocols = web.Protocols(false, false, true)
+	srv.Start()
+	defer srv.Close()
+
+	p := new(http.Protocols)
+	p.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: p}}
+	testH2(t, client, srv.URL, &calls, flushed)
+
+	// 开启 h2c 后, HTTP/1.1 连接仍然可以进行协议升级, 逐跳响应头不会被移除
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second * 3))
+	conn.Write([]byte("GET /upgrade HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 升级后的数据直接从连接中读取
+	body, _ := io.ReadAll(br)
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Upgrade") != "websocket" || string(body) != "upgraded" {
+		t.Fatalf("协议升级失败, code: %d, header: %v, body: %s", resp.StatusCode, resp.Header, body)
+	}
+}
+
+// TestHTTP2Disabled 未开启 http2 时, tls 连接只协商 HTTP/1.1
+func TestHTTP2Disabled(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewUnstartedServer(newH2Handler(t, &calls, make(chan struct{})))
+	srv.Config.Protocols = web.Protocols(true, false, false)
+	srv.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/hop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 1 || !strings.EqualFold(resp.Header.Get("Connection"), "keep-alive") {
+		t.Fatalf("期望使用 HTTP/1.1 并保留响应头, 协议: %s, header: %v", resp.Proto, resp.Header)
+	}
+}
@@ -3,53 +3,119 @@ package web
 import (
 	"net/http"
 	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
 	"github.com/gin-gonic/gin"
 )
 
 // MatchRouteKey 存储在 gin 上下文的路由匹配字段
 const MatchRouteKey = "matchRoute"
 
+// matchedRuleKey 当前请求匹配到的路由规则, 存放到 Gin 上下文
+const matchedRuleKey = "matchedRule"
+
+var (
+	// routeRequests 各路由规则的请求数
+	routeRequests = metrics.NewCounterVec("ge2o_http_requests_total", "各路由规则处理的请求数", "route", "code")
+
+	// routeDuration 各路由规则的处理耗时
+	routeDuration = metrics.NewHistogramVec("ge2o_http_request_duration_seconds", "各路由规则的处理耗时", nil, "route")
+)
+
+// handleMetrics 以 Prometheus 文本格式输出统计指标
+func handleMetrics(c *gin.Context) {
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	if err := metrics.Write(c.Writer); err != nil {
+		logs.Error("输出统计指标失败: %v", err)
+	}
+}
+
+// ruleDef 路由规则定义
+type ruleDef struct {
+	// name 规则名称
+	name string
+
+	// pattern 匹配请求 uri 的正则表达式
+	pattern string
+
+	// handler 请求处理器
+	handler func(*gin.Context)
+
+	// match 除 uri 之外的附加匹配条件, 为 nil 时只匹配 uri
+	match func(*gin.Context) bool
+}
+
+// rule 编译完成的路由规则
+type rule struct {
+	name    string
+	reg     *regexp.Regexp
+	handler gin.HandlerFunc
+	match   func(*gin.Context) bool
+}
+
+// routeMatcher 匹配路由规则, 并统计各路由规则的请求数与处理耗时
+//
+// 需要注册在 RequestCacher 之前, 命中缓存的请求同样会计入所属路由规则
+func routeMatcher() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead {
+			return
+		}
+
+		// 依次匹配路由规则, 找到对应的处理器
+		for _, rule := range *rules.Load() {
+			if !rule.reg.MatchString(c.Request.RequestURI) {
+				continue
+			}
+			if rule.match != nil && !rule.match(c) {
+				continue
+			}
+			c.Set(matchedRuleKey, rule)
+			c.Set(MatchRouteKey, rule.reg.String())
+			c.Set(constant.RouteSubMatchGinKey, rule.reg.FindStringSubmatch(c.Request.RequestURI))
+
+			start := time.Now()
+			c.Next()
+			routeRequests.Inc(rule.name, strconv.Itoa(c.Writer.Status()))
+			routeDuration.Observe(time.Since(start).Seconds(), rule.name)
+			return
+		}
+	}
+}
+
 // globalDftHandler 全局默认兜底的请求处理器
+//
+// 使用 routeMatcher 匹配到的路由规则处理请求
 func globalDftHandler(c *gin.Context) {
 	if c.Request.Method == http.MethodHead {
 		c.String(http.StatusOK, "")
 		return
 	}
 
-	// 依次匹配路由规则, 找到其他的处理器
-	for _, rule := range rules {
-		reg := rule[0].(*regexp.Regexp)
-		if reg.MatchString(c.Request.RequestURI) {
-			c.Set(MatchRouteKey, reg.String())
-			c.Set(constant.RouteSubMatchGinKey, reg.FindStringSubmatch(c.Request.RequestURI))
-			rule[1].(gin.HandlerFunc)(c)
-			return
-		}
+	if v, ok := c.Get(matchedRuleKey); ok {
+		v.(rule).handler(c)
 	}
 }
 
 // compileRules 编译路由的正则表达式
-func compileRules(rs [][2]any) [][2]any {
-	newRs := make([][2]any, 0, len(rs))
-	for _, rule := range rs {
-		reg, err := regexp.Compile(rule[0].(string))
+func compileRules(defs []ruleDef) []rule {
+	rs := make([]rule, 0, len(defs))
+	for _, def := range defs {
+		reg, err := regexp.Compile(def.pattern)
 		if err != nil {
-			logs.Error("路由正则编译失败, pattern: %v, error: %v", rule[0], err)
+			logs.Error("路由正则编译失败, pattern: %v, error: %v", def.pattern, err)
 			continue
 		}
-		rule[0] = reg
-
-		rawHandler, ok := rule[1].(func(*gin.Context))
-		if !ok {
-			logs.Error("错误的请求处理器, pattern: %v", rule[0])
+		if def.handler == nil {
+			logs.Error("错误的请求处理器, pattern: %v", def.pattern)
 			continue
 		}
-		var handler gin.HandlerFunc = rawHandler
-		rule[1] = handler
-		newRs = append(newRs, rule)
+		rs = append(rs, rule{name: def.name, reg: reg, handler: def.handler, match: def.match})
 	}
-	return newRs
+	return rs
 }
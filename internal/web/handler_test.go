@@ -0,0 +1,93 @@
+package web_test
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sample 读取指标样本的值, 不存在时返回 0
+func sample(t *testing.T, series string) float64 {
+	var buf bytes.Buffer
+	if err := metrics.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), series+" "); ok {
+			f, _ := strconv.ParseFloat(v, 64)
+			return f
+		}
+	}
+	return 0
+}
+
+func TestRouteMetrics(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := `emby:
+  host: http://127.0.0.1:8096
+openlist:
+  host: http://127.0.0.1:5244
+  token: stub-token
+cache:
+  enable: true
+  routes:
+    - pattern: (?i)^/ge2o/metrics
+routes:
+  - name: blocked
+    pattern: (?i)^/blocked
+    action: reject
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	defer cache.PurgeRegex(regexp.MustCompile(`^/ge2o/metrics`))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	web.InitRouter(r)
+	get := func(uri string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri, nil))
+		cache.WaitingForHandleChan()
+		return w
+	}
+
+	// 命中缓存的请求同样计入所属的路由规则
+	const metricsSeries = `ge2o_http_requests_total{route="metrics",code="200"}`
+	before := sample(t, metricsSeries)
+	first, second := get("/ge2o/metrics"), get("/ge2o/metrics")
+	if first.Body.String() != second.Body.String() {
+		t.Fatal("期望第二次请求命中缓存")
+	}
+	if got := sample(t, metricsSeries) - before; got != 2 {
+		t.Errorf("期望统计 2 次请求, 实际: %v", got)
+	}
+
+	// 自定义路由使用规则名称作为标签
+	const blockedSeries = `ge2o_http_requests_total{route="blocked",code="403"}`
+	before = sample(t, blockedSeries)
+	if w := get("/blocked"); w.Code != http.StatusForbidden {
+		t.Fatalf("期望拒绝请求, 实际: %d", w.Code)
+	}
+	if got := sample(t, blockedSeries) - before; got != 1 {
+		t.Errorf("期望统计 1 次请求, 实际: %v", got)
+	}
+}
@@ -2,15 +2,35 @@ package web
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 	"github.com/gin-gonic/gin"
 )
 
+// accessLog json 格式的访问日志
+type accessLog struct {
+	Time         string  `json:"time"`
+	Level        string  `json:"level"`
+	RequestId    string  `json:"request_id"`
+	Port         string  `json:"port"`
+	Method       string  `json:"method"`
+	Uri          string  `json:"uri"`
+	Route        string  `json:"route"`
+	Status       int     `json:"status"`
+	LatencyMs    float64 `json:"latency_ms"`
+	ClientIp     string  `json:"client_ip"`
+	UA           string  `json:"ua"`
+	ItemId       string  `json:"item_id,omitempty"`
+	RedirectHost string  `json:"redirect_host,omitempty"`
+	CacheHit     bool    `json:"cache_hit"`
+}
+
 func CustomLogger(port string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -19,9 +39,30 @@ func CustomLogger(port string) gin.HandlerFunc {
 		c.Next()
 
 		// 记录日志
-		fmt.Printf("%s %s | %s | %s | %s | %s %s | %s %s\n",
+		if logs.JsonMode() {
+			logs.JSON(accessLog{
+				Time:         start.Format(time.RFC3339Nano),
+				Level:        "access",
+				RequestId:    c.GetString(constant.RequestIdGinKey),
+				Port:         port,
+				Method:       c.Request.Method,
+				Uri:          c.Request.RequestURI,
+				Route:        c.GetString(MatchRouteKey),
+				Status:       c.Writer.Status(),
+				LatencyMs:    float64(time.Since(start).Microseconds()) / 1000,
+				ClientIp:     c.ClientIP(),
+				UA:           c.Request.UserAgent(),
+				ItemId:       c.GetString(constant.ItemIdGinKey),
+				RedirectHost: redirectHost(c),
+				CacheHit:     c.GetBool(constant.CacheHitGinKey),
+			})
+			return
+		}
+
+		fmt.Printf("%s %s | %s | %s | %s | %s | %s %s | %s %s\n",
 			colors.ToYellow("[ge2o:"+constant.CurrentVersion+"]"),
 			start.Format("2006-01-02 15:04:05"),
+			c.GetString(constant.RequestIdGinKey),
 			colorStatusCode(c.Writer.Status()),
 			time.Since(start),
 			c.ClientIP(),
@@ -33,6 +74,18 @@ func CustomLogger(port string) gin.HandlerFunc {
 	}
 }
 
+// redirectHost 获取重定向响应的目标 host, 非重定向响应返回空字符串
+func redirectHost(c *gin.Context) string {
+	if !https.IsRedirectCode(c.Writer.Status()) {
+		return ""
+	}
+	u, err := url.Parse(c.Writer.Header().Get("Location"))
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
 // colorStatusCode 将响应码打上颜色标记
 func colorStatusCode(code int) string {
 	str := strconv.Itoa(code)
@@ -0,0 +1,31 @@
+package web
+
+import (
+	"regexp"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/randoms"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validRequestId 允许沿用的外部请求 id 格式
+var validRequestId = regexp.MustCompile(`^[0-9A-Za-z._-]{1,64}$`)
+
+// requestIdSetter 为每个请求生成请求 id, 设置到响应头中, 并通过请求的 context 向下传递
+//
+// 反向代理或者服务内部自请求传入了合法的请求 id 时会沿用, 方便串联日志
+func requestIdSetter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqId := c.GetHeader(constant.HeaderRequestId)
+		if !validRequestId.MatchString(reqId) {
+			reqId = randoms.RandomHex(16)
+		}
+		c.Set(constant.RequestIdGinKey, reqId)
+		c.Header(constant.HeaderRequestId, reqId)
+
+		c.Request = c.Request.WithContext(logs.WithRequestId(c.Request.Context(), reqId))
+		c.Next()
+	}
+}
@@ -1,89 +1,119 @@
 package web
 
 import (
+	"sync/atomic"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/m3u8"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/admin"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webhook"
 
 	"github.com/gin-gonic/gin"
 )
 
-// rules 预定义路由拦截规则, 以及相应的处理器
+// rules 路由拦截规则, 按顺序匹配
 //
-// 每个规则为一个切片, 参数分别是: 正则表达式, 处理器
-var rules [][2]any
+// 配置重载时会重新生成规则, 因此使用原子指针存储
+var rules atomic.Pointer[[]rule]
+
+// builtinRules 预定义路由拦截规则, 以及相应的处理器
+//
+// 规则名称可以在自定义路由中通过 builtin 动作引用
+func builtinRules() []ruleDef {
+	return []ruleDef{
+		// 管理接口
+		{name: "admin", pattern: constant.Reg_Admin, handler: admin.Handle},
+		// 统计指标
+		{name: "metrics", pattern: constant.Reg_Metrics, handler: handleMetrics},
+		// emby 通知
+		{name: "webhook", pattern: constant.Reg_Webhook, handler: webhook.Handle},
 
-func initRulePatterns() {
-	logs.Info("正在初始化路由规则...")
-	rules = compileRules([][2]any{
 		// websocket
-		{constant.Reg_Socket, emby.ProxySocket()},
+		{name: "socket", pattern: constant.Reg_Socket, handler: emby.ProxySocket()},
 
 		// PlaybackInfo 接口
-		{constant.Reg_PlaybackInfo, emby.TransferPlaybackInfo},
+		{name: "playback-info", pattern: constant.Reg_PlaybackInfo, handler: emby.TransferPlaybackInfo},
 
 		// 播放停止时, 辅助请求 Progress 记录进度
-		{constant.Reg_PlayingStopped, emby.PlayingStoppedHelper},
+		{name: "playing-stopped", pattern: constant.Reg_PlayingStopped, handler: emby.PlayingStoppedHelper},
 		// 拦截无效的进度报告
-		{constant.Reg_PlayingProgress, emby.PlayingProgressHelper},
+		{name: "playing-progress", pattern: constant.Reg_PlayingProgress, handler: emby.PlayingProgressHelper},
 
 		// Items 接口
-		{constant.Reg_UserItems, emby.LoadCacheItems},
+		{name: "user-items", pattern: constant.Reg_UserItems, handler: emby.LoadCacheItems},
 		// 代理 Items 并添加转码版本信息
-		{constant.Reg_UserEpisodeItems, emby.ProxyAddItemsPreviewInfo},
+		{name: "user-episode-items", pattern: constant.Reg_UserEpisodeItems, handler: emby.ProxyAddItemsPreviewInfo},
 		// 随机列表接口
-		{constant.Reg_UserItemsRandomResort, emby.ResortRandomItems},
+		{name: "user-items-random-resort", pattern: constant.Reg_UserItemsRandomResort, handler: emby.ResortRandomItems},
 		// 代理原始的随机列表接口, 去除 limit 限制, 并进行缓存
-		{constant.Reg_UserItemsRandomWithLimit, emby.RandomItemsWithLimit},
+		{name: "user-items-random-with-limit", pattern: constant.Reg_UserItemsRandomWithLimit, handler: emby.RandomItemsWithLimit},
 		// 代理 Latest 接口, 解码媒体的 Path 字段
-		{constant.Reg_UserLatestItems, emby.ProxyLatestItems},
+		{name: "user-latest-items", pattern: constant.Reg_UserLatestItems, handler: emby.ProxyLatestItems},
 
 		// 重排序剧集
-		{constant.Reg_ShowEpisodes, emby.ResortEpisodes},
+		{name: "show-episodes", pattern: constant.Reg_ShowEpisodes, handler: emby.ResortEpisodes},
 
 		// 字幕长时间缓存
-		{constant.Reg_VideoSubtitles, emby.ProxySubtitles},
+		{name: "video-subtitles", pattern: constant.Reg_VideoSubtitles, handler: emby.ProxySubtitles},
 
 		// 资源重定向到直链
-		{constant.Reg_ResourceStream, emby.Redirect2OpenlistLink},
+		{name: "resource-stream", pattern: constant.Reg_ResourceStream, handler: emby.Redirect2OpenlistLink},
 		// master 重定向到本地 m3u8 代理
-		{constant.Reg_ResourceMaster, emby.Redirect2Transcode},
+		{name: "resource-master", pattern: constant.Reg_ResourceMaster, handler: emby.Redirect2Transcode},
 		// main 路由到直链接口
-		{constant.Reg_ResourceMain, emby.Redirect2Transcode},
+		{name: "resource-main", pattern: constant.Reg_ResourceMain, handler: emby.Redirect2Transcode},
 		// 处理 original 资源
-		{constant.Reg_ResourceOriginal, emby.ProxyOriginalResource},
+		{name: "resource-original", pattern: constant.Reg_ResourceOriginal, handler: emby.ProxyOriginalResource},
 		// m3u8 转码播放列表
-		{constant.Reg_ProxyPlaylist, m3u8.ProxyPlaylist},
+		{name: "proxy-playlist", pattern: constant.Reg_ProxyPlaylist, handler: m3u8.ProxyPlaylist},
 		// ts 重定向到直链
-		{constant.Reg_ProxyTs, m3u8.ProxyTsLink},
+		{name: "proxy-ts", pattern: constant.Reg_ProxyTs, handler: m3u8.ProxyTsLink},
 		// m3u8 字幕
-		{constant.Reg_ProxySubtitle, m3u8.ProxySubtitle},
+		{name: "proxy-subtitle", pattern: constant.Reg_ProxySubtitle, handler: m3u8.ProxySubtitle},
 
 		// 资源下载, 重定向到直链
-		{constant.Reg_ItemDownload, emby.Redirect2OpenlistLink},
-		{constant.Reg_ItemSyncDownload, emby.HandleSyncDownload},
+		{name: "item-download", pattern: constant.Reg_ItemDownload, handler: emby.Redirect2OpenlistLink},
+		{name: "item-sync-download", pattern: constant.Reg_ItemSyncDownload, handler: emby.HandleSyncDownload},
 
 		// 处理图片请求
-		{constant.Reg_Images, emby.HandleImages},
+		{name: "images", pattern: constant.Reg_Images, handler: emby.HandleImages},
 
 		// web cors 处理
-		{constant.Reg_VideoModWebDefined, emby.ChangeBaseVideoModuleCorsDefined},
+		{name: "video-mod-web-defined", pattern: constant.Reg_VideoModWebDefined, handler: emby.ChangeBaseVideoModuleCorsDefined},
 
 		// 代理首页, 注入自定义脚本
-		{constant.Reg_IndexHtml, emby.ProxyIndexHtml},
+		{name: "index-html", pattern: constant.Reg_IndexHtml, handler: emby.ProxyIndexHtml},
 		// 响应自定义脚本
-		{constant.Route_CustomJs, emby.ProxyCustomJs},
+		{name: "custom-js", pattern: constant.Route_CustomJs, handler: emby.ProxyCustomJs},
 		// 响应自定义样式
-		{constant.Route_CustomCss, emby.ProxyCustomCss},
+		{name: "custom-css", pattern: constant.Route_CustomCss, handler: emby.ProxyCustomCss},
 
 		// 根路径重定向到首页
-		{constant.Reg_Root, emby.ProxyRoot},
+		{name: "root", pattern: constant.Reg_Root, handler: emby.ProxyRoot},
 
 		// 其余资源走重定向回源
-		{constant.Reg_All, emby.ProxyOrigin},
-	})
-	logs.Success("路由规则初始化完成")
+		{name: "origin", pattern: constant.Reg_All, handler: emby.ProxyOrigin},
+	}
+}
+
+func initRulePatterns() {
+	logs.Info("正在初始化路由规则...")
+	builtins := builtinRules()
+	before, after := customRules(config.C().Routes, builtins)
+
+	// 位置为 after 的自定义规则放在兜底回源规则之前
+	defs := make([]ruleDef, 0, len(before)+len(builtins)+len(after))
+	defs = append(defs, before...)
+	defs = append(defs, builtins[:len(builtins)-1]...)
+	defs = append(defs, after...)
+	defs = append(defs, builtins[len(builtins)-1])
+
+	rs := compileRules(defs)
+	rules.Store(&rs)
+	logs.Success("路由规则初始化完成, 自定义规则数: %d", len(before)+len(after))
 }
 
 // initRoutes 初始化路由
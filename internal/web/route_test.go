@@ -0,0 +1,16 @@
+package web_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web"
+)
+
+// TestBuiltinRouteNames 配置校验使用的内置路由名称需要与实际注册的规则保持一致
+func TestBuiltinRouteNames(t *testing.T) {
+	if names := web.BuiltinRuleNames(); !slices.Equal(names, constant.BuiltinRoutes) {
+		t.Fatalf("内置路由名称不一致\n规则: %v\n常量: %v", names, constant.BuiltinRoutes)
+	}
+}
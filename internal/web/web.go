@@ -1,9 +1,13 @@
 package web
 
 import (
+	"context"
 	"crypto/tls"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	_ "net/http/pprof"
+	"sync"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
@@ -14,80 +18,175 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// server 监听中的服务, 以及对应的启动方式
+type server struct {
+	name  string
+	srv   *http.Server
+	serve func() error
+}
+
+// running 正在运行的服务, 由 Shutdown 负责停止
+var running []server
+
 // Listen 监听指定端口
-func Listen() error {
+//
+// 该函数会阻塞, 直到任意服务异常退出, 或者 ctx 被取消;
+// 返回后服务仍在运行, 需要调用 Shutdown 停止服务
+func Listen(ctx context.Context) error {
 	initRulePatterns()
+	config.OnReload(initRulePatterns)
+	config.OnReload(certs.reset)
 
-	errChanHTTP, errChanHTTPS := make(chan error, 1), make(chan error, 1)
-	if !config.C.Ssl.Enable {
-		go listenHTTP(errChanHTTP)
-	} else if config.C.Ssl.SinglePort {
-		go listenHTTPS(errChanHTTPS)
-	} else {
-		go listenHTTP(errChanHTTP)
-		go listenHTTPS(errChanHTTPS)
+	if err := config.C().Ssl.EnsureCert(); err != nil {
+		return err
+	}
+
+	var servers []server
+	if !config.C().Ssl.Enable || !config.C().Ssl.SinglePort {
+		for _, l := range config.C().Server.HttpListeners() {
+			servers = append(servers, newHTTPServer(l))
+		}
+	}
+	if config.C().Ssl.Enable {
+		for _, l := range config.C().Server.HttpsListeners() {
+			servers = append(servers, newHTTPSServer(l))
+		}
+	}
+	if config.C().Server.Pprof != "" {
+		servers = append(servers, newPprofServer())
+	}
+
+	running = servers
+	errChan := make(chan error, len(servers))
+	for _, s := range servers {
+		go func() {
+			if err := s.serve(); !errors.Is(err, http.ErrServerClosed) {
+				errChan <- fmt.Errorf("%s 服务异常: %v", s.name, err)
+			}
+		}()
 	}
 
 	select {
-	case err := <-errChanHTTP:
-		log.Fatal("http 服务异常: ", err)
-	case err := <-errChanHTTPS:
-		log.Fatal("https 服务异常: ", err)
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		logs.Info("收到退出信号, 正在停止服务...")
+		return nil
+	}
+}
+
+// Shutdown 停止接收新的连接, 并在 ctx 结束之前等待处理中的请求完成
+func Shutdown(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(running))
+	for i, s := range running {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.srv.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s 服务停止异常: %v", s.name, err)
+			}
+		}()
 	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	logs.Success("所有服务已停止")
 	return nil
 }
 
 // initRouter 初始化路由引擎
 func initRouter(r *gin.Engine) {
 	r.Use(referrerPolicySetter())
+	r.Use(emby.UpstreamResolver())
 	r.Use(emby.ApiKeyChecker())
 	r.Use(emby.DownloadStrategyChecker())
-	if config.C.Cache.Enable {
-		r.Use(cache.CacheableRouteMarker())
-		r.Use(cache.RequestCacher())
-	}
+	r.Use(routeMatcher())
+	r.Use(cache.CacheableRouteMarker())
+	r.Use(cache.RequestCacher())
 	initRoutes(r)
 }
 
-// listenHTTP 在指定端口上监听 http 服务
-//
-// 出现错误时, 会写入 errChan 中
-func listenHTTP(errChan chan error) {
+// newEngine 初始化监听地址对应的路由引擎
+func newEngine(l webport.Listener) *gin.Engine {
+	// unix 套接字没有端口, 日志中展示套接字路径
+	label := l.Port
+	if l.IsUnix() {
+		label = l.String()
+	}
+
 	r := gin.New()
+	r.Use(requestIdSetter())
 	r.Use(gin.Recovery())
-	r.Use(CustomLogger(webport.HTTP))
+	r.Use(CustomLogger(label))
 	r.Use(func(c *gin.Context) {
-		c.Set(webport.GinKey, webport.HTTP)
+		c.Set(webport.GinKey, l.Port)
 	})
 	initRouter(r)
-	logs.Info("在端口【%s】上启动 HTTP 服务", webport.HTTP)
-	err := r.Run("0.0.0.0:" + webport.HTTP)
-	errChan <- err
-	close(errChan)
+	return r
 }
 
-// listenHTTPS 在指定端口上监听 https 服务
-//
-// 出现错误时, 会写入 errChan 中
-func listenHTTPS(errChan chan error) {
-	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(CustomLogger(webport.HTTPS))
-	r.Use(func(c *gin.Context) {
-		c.Set(webport.GinKey, webport.HTTPS)
-	})
-	initRouter(r)
-	logs.Info("在端口【%s】上启动 HTTPS 服务", webport.HTTPS)
-	ssl := config.C.Ssl
+// newHTTPServer 初始化 http 服务
+func newHTTPServer(l webport.Listener) server {
+	srv := &http.Server{
+		Handler:   stripHopHeaders(newEngine(l)),
+		Protocols: protocols(false, false, config.C().Server.H2c),
+	}
+	return server{
+		name: "http [" + l.String() + "]",
+		srv:  srv,
+		serve: func() error {
+			ln, err := l.Listen()
+			if err != nil {
+				return err
+			}
+			logs.Info("在【%s】上启动 HTTP 服务", l)
+			return srv.Serve(ln)
+		},
+	}
+}
 
+// newHTTPSServer 初始化 https 服务
+func newHTTPSServer(l webport.Listener) server {
+	// HTTP/2 默认关闭, 需要通过 server.http2 开启
 	srv := &http.Server{
-		Addr:    "0.0.0.0:" + webport.HTTPS,
-		Handler: r,
+		Handler:   stripHopHeaders(newEngine(l)),
+		TLSConfig: &tls.Config{GetCertificate: certs.get},
+		Protocols: protocols(true, config.C().Server.Http2, false),
 	}
-	// 禁用 HTTP/2
-	srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
 
-	err := srv.ListenAndServeTLS(ssl.CrtPath(), ssl.KeyPath())
-	errChan <- err
-	close(errChan)
+	return server{
+		name: "https [" + l.String() + "]",
+		srv:  srv,
+		serve: func() error {
+			ln, err := l.Listen()
+			if err != nil {
+				return err
+			}
+			logs.Info("在【%s】上启动 HTTPS 服务", l)
+			// 证书由 GetCertificate 动态提供, 以支持配置热重载
+			return srv.ServeTLS(ln, "", "")
+		},
+	}
+}
+
+// newPprofServer 初始化性能分析服务
+func newPprofServer() server {
+	// 地址已在配置初始化时校验过
+	l, _ := webport.Parse(config.C().Server.Pprof)
+	srv := &http.Server{Handler: http.DefaultServeMux}
+	return server{
+		name: "pprof [" + l.String() + "]",
+		srv:  srv,
+		serve: func() error {
+			ln, err := l.Listen()
+			if err != nil {
+				return err
+			}
+			logs.Info("在【%s】上启动 pprof 服务", l)
+			return srv.Serve(ln)
+		},
+	}
 }
@@ -0,0 +1,134 @@
+// emby 通知接收接口, 收到媒体库变更通知后清理相关条目的缓存
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+
+	// HeaderKeySecret 携带共享密钥的请求头
+	HeaderKeySecret = "X-Ge2o-Webhook-Secret"
+
+	// MaxPayloadSize 通知请求体的最大长度
+	MaxPayloadSize = 1 << 20
+)
+
+// payload emby 通知的请求体, 只解析需要用到的字段
+type payload struct {
+	Event string `json:"Event"`
+	Item  struct {
+		Id string `json:"Id"`
+	} `json:"Item"`
+}
+
+// Handle 通知接收接口统一入口
+//
+// 支持 application/json 格式的请求体, 以及旧版通知插件 multipart/form-data 格式的 data 字段;
+// 可以通过 upstream 参数指定通知来源的 emby 上游名称, 为空时清理所有上游的缓存
+func Handle(c *gin.Context) {
+	cfg := config.C().Webhook
+	if !cfg.Enable {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if !authorized(c, cfg.Secret) {
+		logs.Ctx(c.Request.Context()).Warn("通知接口鉴权失败, ip: %s", c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"msg": "鉴权失败"})
+		return
+	}
+
+	if c.Request.Method != http.MethodPost {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"msg": "仅支持 POST 请求"})
+		return
+	}
+
+	upstream := c.Query("upstream")
+	if upstream != "" {
+		if _, ok := config.C().FindEmby(upstream); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"msg": "emby 上游不存在: " + upstream})
+			return
+		}
+	}
+
+	p, err := parsePayload(c)
+	if err != nil {
+		logs.Ctx(c.Request.Context()).Warn("通知解析失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"msg": "通知解析失败: " + err.Error()})
+		return
+	}
+
+	purged := purge(upstream, p)
+	if purged > 0 {
+		logs.Ctx(c.Request.Context()).Info("收到 emby 通知 [%s], itemId: %s, 清理缓存 %d 条", p.Event, p.Item.Id, purged)
+	}
+	c.JSON(http.StatusOK, gin.H{"event": p.Event, "item_id": p.Item.Id, "purged": purged})
+}
+
+// authorized 校验请求中的共享密钥, 未配置密钥时不校验
+func authorized(c *gin.Context, secret string) bool {
+	if secret == "" {
+		return true
+	}
+	reqSecret := c.GetHeader(HeaderKeySecret)
+	if reqSecret == "" {
+		reqSecret = c.Query("secret")
+	}
+	return subtle.ConstantTimeCompare([]byte(reqSecret), []byte(secret)) == 1
+}
+
+// parsePayload 解析通知请求体
+func parsePayload(c *gin.Context) (payload, error) {
+	var p payload
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxPayloadSize)
+
+	var raw []byte
+	ct := c.ContentType()
+	if ct == gin.MIMEMultipartPOSTForm || ct == gin.MIMEPOSTForm {
+		raw = []byte(c.PostForm("data"))
+	} else {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return p, err
+		}
+		raw = body
+	}
+
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return p, err
+	}
+	p.Event = strings.ToLower(strings.TrimSpace(p.Event))
+	return p, nil
+}
+
+// purge 根据通知类型清理缓存, 返回清理的条目数
+func purge(upstream string, p payload) int {
+	switch {
+	case p.Event == "library.new":
+		// 新增条目会影响所有列表, 同时可能是替换了资源文件
+		return emby.PurgeItemLists(upstream, "") + emby.PurgeItemCache(upstream, p.Item.Id)
+	case strings.HasPrefix(p.Event, "playback."),
+		p.Event == "item.rate",
+		p.Event == "item.markplayed",
+		p.Event == "item.markunplayed":
+		// 只有用户数据发生变更, 不影响播放信息以及资源直链
+		if p.Item.Id == "" {
+			return 0
+		}
+		return emby.PurgeItemLists(upstream, p.Item.Id)
+	default:
+		// item.updated, item.deleted, library.deleted 等
+		return emby.PurgeItemCache(upstream, p.Item.Id)
+	}
+}
@@ -0,0 +1,128 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSecret = "webhook-secret"
+
+func initConfig(t *testing.T, enable bool) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	content := fmt.Sprintf(`emby:
+  host: http://127.0.0.1:8096
+openlist:
+  host: http://127.0.0.1:5244
+  token: stub-token
+webhook:
+  enable: %v
+  secret: %s
+`, enable, testSecret)
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ReadFromFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Any("/*vars", webhook.Handle)
+	return r
+}
+
+// multipartBody 模拟旧版通知插件的请求体
+func multipartBody(t *testing.T, data string) (io.Reader, string) {
+	var sb strings.Builder
+	mw := multipart.NewWriter(&sb)
+	if err := mw.WriteField("data", data); err != nil {
+		t.Fatal(err)
+	}
+	mw.Close()
+	return strings.NewReader(sb.String()), mw.FormDataContentType()
+}
+
+func TestHandle(t *testing.T) {
+	initConfig(t, true)
+	r := newEngine()
+
+	const updated = `{"Event":"item.updated","Item":{"Id":"12345"}}`
+	formBody, formType := multipartBody(t, `{"Event":"library.new","Item":{"Id":"678"}}`)
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		body      io.Reader
+		ct        string
+		want      int
+		wantEvent string
+	}{
+		{"缺少密钥", http.MethodPost, "/ge2o/webhook", strings.NewReader(updated), "application/json", http.StatusUnauthorized, ""},
+		{"错误密钥", http.MethodPost, "/ge2o/webhook?secret=wrong", strings.NewReader(updated), "application/json", http.StatusUnauthorized, ""},
+		{"请求方法不匹配", http.MethodGet, "/ge2o/webhook?secret=" + testSecret, nil, "", http.StatusMethodNotAllowed, ""},
+		{"上游不存在", http.MethodPost, "/ge2o/webhook?upstream=unknown&secret=" + testSecret, strings.NewReader(updated), "application/json", http.StatusBadRequest, ""},
+		{"请求体格式错误", http.MethodPost, "/ge2o/webhook?secret=" + testSecret, strings.NewReader("{"), "application/json", http.StatusBadRequest, ""},
+		{"json 通知", http.MethodPost, "/ge2o/webhook?secret=" + testSecret, strings.NewReader(updated), "application/json", http.StatusOK, "item.updated"},
+		{"表单通知", http.MethodPost, "/ge2o/webhook?secret=" + testSecret, formBody, formType, http.StatusOK, "library.new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.uri, tt.body)
+			if tt.ct != "" {
+				req.Header.Set("Content-Type", tt.ct)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("期望状态码: %d, 实际: %d, 响应: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.wantEvent == "" {
+				return
+			}
+			var resp struct {
+				Event string `json:"event"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Event != tt.wantEvent {
+				t.Errorf("期望事件: %s, 实际响应: %s", tt.wantEvent, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleSecretHeader(t *testing.T) {
+	initConfig(t, true)
+	req := httptest.NewRequest(http.MethodPost, "/ge2o/webhook", strings.NewReader(`{"Event":"playback.stop","Item":{"Id":"1"}}`))
+	req.Header.Set(webhook.HeaderKeySecret, testSecret)
+	w := httptest.NewRecorder()
+	newEngine().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("请求头携带密钥时期望状态码 200, 实际: %d", w.Code)
+	}
+}
+
+func TestHandleDisabled(t *testing.T) {
+	initConfig(t, false)
+	req := httptest.NewRequest(http.MethodPost, "/ge2o/webhook?secret="+testSecret, strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	newEngine().ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("通知接口关闭时期望状态码 404, 实际: %d", w.Code)
+	}
+}
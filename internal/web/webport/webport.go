@@ -1,5 +1,16 @@
 package webport
 
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HTTPS, HTTP 命令行参数指定的端口, 未配置 server.listen 时使用
 var (
 	HTTPS = "8094"
 	HTTP  = "8095"
@@ -7,4 +18,104 @@ var (
 
 const (
 	GinKey = "port"
+
+	// UnixPrefix unix 套接字监听地址前缀
+	UnixPrefix = "unix:"
 )
+
+// Listener 监听地址
+type Listener struct {
+	// Network 网络类型: tcp, unix
+	Network string
+
+	// Address tcp 监听地址 (host:port) 或 unix 套接字路径
+	Address string
+
+	// Port tcp 监听端口, unix 套接字为空
+	Port string
+}
+
+// Parse 解析监听地址
+//
+// 支持的格式: ":8095", "0.0.0.0:8095", "[::]:8095", "unix:/run/ge2o.sock"
+func Parse(addr string) (Listener, error) {
+	addr = strings.TrimSpace(addr)
+	if path, ok := strings.CutPrefix(addr, UnixPrefix); ok {
+		if path == "" {
+			return Listener{}, errors.New("unix 套接字路径不能为空")
+		}
+		return Listener{Network: "unix", Address: path}, nil
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return Listener{}, fmt.Errorf("监听地址格式错误: %s, %v", addr, err)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return Listener{}, fmt.Errorf("监听端口错误: %s", addr)
+	}
+	return Listener{Network: "tcp", Address: addr, Port: port}, nil
+}
+
+// Default 根据命令行参数指定的端口生成默认的监听地址
+func Default(port string) Listener {
+	return Listener{Network: "tcp", Address: "0.0.0.0:" + port, Port: port}
+}
+
+// IsUnix 是否为 unix 套接字
+func (l Listener) IsUnix() bool {
+	return l.Network == "unix"
+}
+
+// String 监听地址的展示名称
+func (l Listener) String() string {
+	if l.IsUnix() {
+		return UnixPrefix + l.Address
+	}
+	return l.Address
+}
+
+// LocalHost 服务内部自请求时使用的 host:port
+//
+// 未指定 ip 时使用环回地址, unix 套接字返回空字符串
+func (l Listener) LocalHost() string {
+	if l.IsUnix() {
+		return ""
+	}
+	host, port, _ := net.SplitHostPort(l.Address)
+	ip := net.ParseIP(host)
+	switch {
+	case host == "", ip != nil && ip.IsUnspecified() && ip.To4() != nil:
+		host = "127.0.0.1"
+	case ip != nil && ip.IsUnspecified():
+		host = "::1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Listen 开始监听
+//
+// 监听 unix 套接字前会移除残留的套接字文件, 并放开文件权限, 方便反向代理访问
+func (l Listener) Listen() (net.Listener, error) {
+	if !l.IsUnix() {
+		return net.Listen(l.Network, l.Address)
+	}
+
+	if stat, err := os.Stat(l.Address); err == nil {
+		if stat.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("路径已存在且不是套接字文件: %s", l.Address)
+		}
+		if err := os.Remove(l.Address); err != nil {
+			return nil, fmt.Errorf("移除残留的套接字文件失败: %v", err)
+		}
+	}
+	ln, err := net.Listen(l.Network, l.Address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(l.Address, 0666); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("设置套接字文件权限失败: %v", err)
+	}
+	return ln, nil
+}
@@ -1,48 +1,103 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
-	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"syscall"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/check"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/m3u8"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist/localtree"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
 	"github.com/gin-gonic/gin"
 )
 
 var ginMode = gin.DebugMode
 
-func main() {
-	go func() { http.ListenAndServe(":60360", nil) }()
+// checkMode 是否以检查模式运行, 只校验配置与上游服务的连通性, 不启动服务
+var checkMode bool
 
+func main() {
 	dataRoot := parseFlag()
 
 	if err := config.ReadFromFile(filepath.Join(dataRoot, "config.yml")); err != nil {
+		if checkMode {
+			fmt.Println(colors.ToRed("[失败] 配置初始化: " + err.Error()))
+			os.Exit(1)
+		}
 		log.Fatal(err)
 	}
 
-	printBanner()
+	if checkMode {
+		fmt.Println(colors.ToGreen("[通过] 配置初始化"))
+		if !check.Report(os.Stdout, check.Run()) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// json 格式的日志需要保证每行都是合法的 json, 不输出 banner
+	if !logs.JsonMode() {
+		printBanner()
+	}
+	go config.Watch()
+
+	// 收到退出信号时, 停止接收新请求, 并等待处理中的任务完成
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	logs.Info("正在初始化本地目录树模块...")
-	if err := localtree.Init(); err != nil {
+	if err := localtree.Init(ctx); err != nil {
+		log.Fatal(colors.ToRed(err.Error()))
+	}
+
+	logs.Info("正在初始化缓存存储...")
+	if err := cache.InitBackend(); err != nil {
+		log.Fatal(colors.ToRed(err.Error()))
+	}
+
+	logs.Info("正在加载磁盘缓存...")
+	if err := cache.InitDisk(); err != nil {
 		log.Fatal(colors.ToRed(err.Error()))
 	}
 
 	logs.Info("正在启动服务...")
 	gin.SetMode(ginMode)
-	if err := web.Listen(); err != nil {
-		log.Fatal(colors.ToRed(err.Error()))
+	listenErr := web.Listen(ctx)
+
+	// 停止服务以及后台任务共用同一个超时时间
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.C().Server.ShutdownTimeoutDuration())
+	defer cancel()
+	if err := web.Shutdown(shutdownCtx); err != nil {
+		logs.Error("%v", err)
+	}
+	stopBackground(shutdownCtx)
+	if listenErr != nil {
+		log.Fatal(colors.ToRed(listenErr.Error()))
+	}
+	logs.Success("程序已退出")
+}
+
+// stopBackground 在 ctx 结束之前停止后台任务
+func stopBackground(ctx context.Context) {
+	logs.Info("正在等待目录树同步任务退出...")
+	if err := localtree.Wait(ctx); err != nil {
+		logs.Warn("%v", err)
 	}
+	cache.Stop()
+	m3u8.Stop()
 }
 
 // parseFlag 转换命令行参数
@@ -51,6 +106,7 @@ func parseFlag() (dataRoot string) {
 	phs := flag.Int("ps", 8098, "HTTPS 服务监听端口")
 	printVersion := flag.Bool("version", false, "查看程序版本")
 	dr := flag.String("dr", ".", "程序数据根目录")
+	flag.BoolVar(&checkMode, "check", false, "检查配置以及上游服务的连通性后退出")
 	flag.Parse()
 
 	if *printVersion {